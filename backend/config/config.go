@@ -11,13 +11,16 @@ import (
 )
 
 type Config struct {
-	App            App            `mapstructure:"app"`
+	App             App             `mapstructure:"app"`
 	SeedSystemAdmin SeedSystemAdmin `mapstructure:"seed_system_admin"`
-	Logger         Logger         `mapstructure:"logger"`
-	JWT            JWT            `mapstructure:"jwt"`
-	Postgres       Postgres       `mapstructure:"postgres"`
-	Redis          Redis          `mapstructure:"redis"`
-	Minio          Minio          `mapstructure:"minio"`
+	Logger          Logger          `mapstructure:"logger"`
+	JWT             JWT             `mapstructure:"jwt"`
+	Lockout         Lockout         `mapstructure:"lockout"`
+	Subscription    Subscription    `mapstructure:"subscription"`
+	Hasher          Hasher          `mapstructure:"hasher"`
+	Postgres        Postgres        `mapstructure:"postgres"`
+	Redis           Redis           `mapstructure:"redis"`
+	Minio           Minio           `mapstructure:"minio"`
 }
 
 type App struct {
@@ -26,6 +29,8 @@ type App struct {
 	Host string `mapstructure:"host"`
 	Env  string `mapstructure:"env"`
 
+	BaseDomain string `mapstructure:"base_domain"`
+
 	TelegramBotToken string `mapstructure:"telegram_bot_token"`
 	TelegramChatID   string `mapstructure:"telegram_chat_id"`
 
@@ -67,9 +72,36 @@ type Logger struct {
 }
 
 type JWT struct {
-	SecretKey            string        `mapstructure:"secret_key"`
+	Algorithm            string        `mapstructure:"algorithm"`
+	KeyRotationInterval  time.Duration `mapstructure:"key_rotation_interval"`
 	AccessExpireMinutes  time.Duration `mapstructure:"access_expire_minutes"`
 	RefreshExpireMinutes time.Duration `mapstructure:"refresh_expire_minutes"`
+	UserAgentDrift       string        `mapstructure:"user_agent_drift"`
+	IPDrift              string        `mapstructure:"ip_drift"`
+	ImpersonationTTL     time.Duration `mapstructure:"impersonation_ttl"`
+	KeyEncryptionKey     string        `mapstructure:"key_encryption_key"`
+}
+
+type Lockout struct {
+	UserMaxAttempts int           `mapstructure:"user_max_attempts"`
+	IPMaxAttempts   int           `mapstructure:"ip_max_attempts"`
+	Window          time.Duration `mapstructure:"window"`
+	BaseDelay       time.Duration `mapstructure:"base_delay"`
+	MaxDelay        time.Duration `mapstructure:"max_delay"`
+}
+
+type Subscription struct {
+	GracePeriod      time.Duration `mapstructure:"grace_period"`
+	ReminderDays     []int         `mapstructure:"reminder_days"`
+	ReminderInterval time.Duration `mapstructure:"reminder_interval"`
+}
+
+type Hasher struct {
+	Algorithm         string `mapstructure:"algorithm"`
+	Argon2Memory      uint32 `mapstructure:"argon2_memory"`
+	Argon2Iterations  uint32 `mapstructure:"argon2_iterations"`
+	Argon2Parallelism uint8  `mapstructure:"argon2_parallelism"`
+	BcryptCost        int    `mapstructure:"bcrypt_cost"`
 }
 
 type Postgres struct {
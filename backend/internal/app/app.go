@@ -2,6 +2,7 @@
 package app
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/asliddinberdiev/eirsystem/config"
@@ -10,12 +11,14 @@ import (
 	"github.com/asliddinberdiev/eirsystem/internal/server"
 	"github.com/asliddinberdiev/eirsystem/internal/service"
 	"github.com/asliddinberdiev/eirsystem/pkg/casbin"
+	"github.com/asliddinberdiev/eirsystem/pkg/hasher"
 	"github.com/asliddinberdiev/eirsystem/pkg/logger"
 	"github.com/asliddinberdiev/eirsystem/pkg/minio"
 	"github.com/asliddinberdiev/eirsystem/pkg/postgres"
 	"github.com/asliddinberdiev/eirsystem/pkg/redis"
 	"github.com/asliddinberdiev/eirsystem/pkg/seed"
 	"github.com/asliddinberdiev/eirsystem/pkg/telegram"
+	"gorm.io/gorm"
 )
 
 func New() {
@@ -31,6 +34,7 @@ func New() {
 	defer log.Sync()
 
 	telegram.Init(log.Named("Telegram"), cfg.App.TelegramBotToken, cfg.App.TelegramChatID)
+	hasher.Init(cfg.Hasher)
 	appLog := log.Named("APP")
 
 	failOnError := func(title string, err error) {
@@ -60,9 +64,14 @@ func New() {
 	defer sqlDB.Close()
 	appLog.Info("Connected to postgres")
 
-	if err := postgres.RunMigrations(sqlDB, "./migrations"); err != nil {
+	migrationDB, err := postgres.NewMigrationDB(&cfg.Postgres)
+	if err != nil {
+		failOnError("Postgres migration connection failed", err)
+	}
+	if err := postgres.RunMigrations(migrationDB, "./migrations"); err != nil {
 		failOnError("Database migration failed", err)
 	}
+	migrationDB.Close()
 	appLog.Info("Database migrations applied")
 
 	enforcer, err := casbin.InitEnforcer(gormPsql, "config/rbac_model.conf")
@@ -71,12 +80,19 @@ func New() {
 	}
 	appLog.Info("Casbin Enforcer initialized")
 
-	if err := seed.SeedSystemAdmin(log.Named("SEED"), gormPsql, cfg.SeedSystemAdmin); err != nil {
+	// Seeds write across tenants, so they run with the row-level security bypass.
+	err = postgres.Bypass(gormPsql).Transaction(func(tx *gorm.DB) error {
+		return seed.SeedSystemAdmin(log.Named("SEED"), tx, cfg.SeedSystemAdmin)
+	})
+	if err != nil {
 		failOnError("System Admin seeding failed", err)
 	}
 
 	if cfg.App.IsDev() {
-		if err := seed.SeedTestUsers(log.Named("SEED_TEST"), gormPsql, enforcer); err != nil {
+		err = postgres.Bypass(gormPsql).Transaction(func(tx *gorm.DB) error {
+			return seed.SeedTestUsers(log.Named("SEED_TEST"), tx, enforcer)
+		})
+		if err != nil {
 			failOnError("Test users seeding failed", err)
 		}
 	}
@@ -88,6 +104,13 @@ func New() {
 	defer redisClient.Close()
 	appLog.Info("Connected to redis")
 
+	watcher, err := casbin.NewWatcher(log.Named("CASBIN"), redisClient.Client, enforcer)
+	if err != nil {
+		failOnError("Casbin watcher init error", err)
+	}
+	defer watcher.Close()
+	appLog.Info("Casbin policy watcher started")
+
 	minioClient, err := minio.New(&cfg.Minio, cfg.App.IsDev(), log.Named("MINIO"))
 	if err != nil {
 		failOnError("Minio connection failed", err)
@@ -97,7 +120,19 @@ func New() {
 	repository := repository.New(cfg, log.Named("REPOSITORY"), gormPsql, redisClient)
 	service := service.New(cfg, log.Named("SERVICE"), minioClient, repository, enforcer)
 
-	h := httpDelivery.New(cfg, log.Named("HTTP"), redisClient.Client, service, enforcer)
+	if err := service.Policy.UpgradePolicyTemplates(); err != nil {
+		failOnError("Policy template upgrade failed", err)
+	}
+	appLog.Info("Policy templates applied")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go service.Tenant.RemindRenewals(ctx)
+
+	h, err := httpDelivery.New(cfg, log.Named("HTTP"), redisClient.Client, service, enforcer)
+	if err != nil {
+		failOnError("HTTP handler init failed", err)
+	}
 	srv := server.New(&cfg.App, log.Named("SERVER"), h.InitRouter())
 
 	if err := srv.Run(); err != nil {
@@ -2,12 +2,18 @@
 package http
 
 import (
+	"context"
+	"net/http"
+
 	"github.com/asliddinberdiev/eirsystem/config"
 	"github.com/asliddinberdiev/eirsystem/internal/delivery/http/middleware"
 	v1 "github.com/asliddinberdiev/eirsystem/internal/delivery/http/v1"
+	"github.com/asliddinberdiev/eirsystem/internal/model"
 	"github.com/asliddinberdiev/eirsystem/internal/service"
+	"github.com/asliddinberdiev/eirsystem/pkg/codes"
 	"github.com/asliddinberdiev/eirsystem/pkg/jwt"
 	"github.com/asliddinberdiev/eirsystem/pkg/logger"
+	"github.com/asliddinberdiev/eirsystem/pkg/response"
 	"github.com/asliddinberdiev/eirsystem/pkg/validator"
 	"github.com/casbin/casbin/v3"
 	"github.com/gin-contrib/cors"
@@ -22,19 +28,47 @@ type Handler struct {
 	jwtManager  *jwt.Manager
 	redisClient *redis.Client
 	svc         *service.Service
-	enforcer    *casbin.Enforcer
+	enforcer    *casbin.SyncedEnforcer
 }
 
-func New(cfg *config.Config, log logger.Logger, redisClient *redis.Client, svc *service.Service, enforcer *casbin.Enforcer) *Handler {
+func New(cfg *config.Config, log logger.Logger, redisClient *redis.Client, svc *service.Service, enforcer *casbin.SyncedEnforcer) (*Handler, error) {
+	jwtManager, err := jwt.New(&cfg.JWT, redisClient)
+	if err != nil {
+		return nil, err
+	}
+
+	jwtManager.OnSecurityEvent(func(ctx context.Context, e jwt.SecurityEvent) {
+		var tenantID *string
+		if e.TenantID != "" {
+			tenantID = &e.TenantID
+		}
+
+		svc.SecurityEvent.Record(ctx, model.SecurityEvent{
+			TenantID:          tenantID,
+			UserID:            e.UserID,
+			SessionID:         e.SessionID,
+			Type:              e.Type,
+			Action:            e.Action,
+			ClientIP:          e.ClientIP,
+			UserAgent:         e.UserAgent,
+			PreviousClientIP:  e.PreviousClientIP,
+			PreviousUserAgent: e.PreviousUserAgent,
+		})
+	})
+
+	if err := jwtManager.IndexLegacySessions(context.Background()); err != nil {
+		log.Warn("Indexing legacy sessions failed", logger.Error(err))
+	}
+
 	return &Handler{
 		cfg:         cfg,
 		log:         log,
 		valid:       validator.New(),
-		jwtManager:  jwt.New(&cfg.JWT, redisClient),
+		jwtManager:  jwtManager,
 		redisClient: redisClient,
 		svc:         svc,
 		enforcer:    enforcer,
-	}
+	}, nil
 }
 
 func (h *Handler) InitRouter() *gin.Engine {
@@ -50,11 +84,25 @@ func (h *Handler) InitRouter() *gin.Engine {
 	router.Use(logger.GinLogger(h.log))
 	router.Use(middleware.NewRateLimiter(h.log, h.redisClient, "120-S", "app"))
 
+	router.GET("/.well-known/jwks.json", h.JWKS)
 	h.initAPI(router)
 
 	return router
 }
 
+// JWKS publishes the public keys that verify access tokens, so other services
+// can validate them without access to the signing keys.
+func (h *Handler) JWKS(c *gin.Context) {
+	set, err := h.jwtManager.JWKS(c.Request.Context())
+	if err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, set)
+}
+
 func (h *Handler) initAPI(router *gin.Engine) {
 	handlerV1 := v1.NewHandler(h.cfg, h.log, h.valid, h.jwtManager, h.svc, h.enforcer)
 
@@ -3,43 +3,198 @@ package middleware
 import (
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 
+	"github.com/asliddinberdiev/eirsystem/internal/model"
 	"github.com/asliddinberdiev/eirsystem/pkg/codes"
 	"github.com/asliddinberdiev/eirsystem/pkg/logger"
+	"github.com/asliddinberdiev/eirsystem/pkg/permission"
 	"github.com/asliddinberdiev/eirsystem/pkg/response"
 	"github.com/casbin/casbin/v3"
 	"github.com/gin-gonic/gin"
 )
 
-func Authorizer(log logger.Logger, e *casbin.Enforcer) gin.HandlerFunc {
+// Permissions holds the permission each protected route requires, keyed by
+// method and route pattern. It is filled while routes are registered and only
+// read afterwards.
+type Permissions map[string]string
+
+// Set declares the permission of a route. The permission has to be in the
+// registry of the permission package, or roles could never be granted it.
+func (p Permissions) Set(method, path, name string) {
+	if !slices.Contains(permission.Known, name) {
+		panic(fmt.Sprintf("route %s %s declares unknown permission %q", method, path, name))
+	}
+	p[method+" "+path] = name
+}
+
+func (p Permissions) Get(method, path string) (string, bool) {
+	permission, ok := p[method+" "+path]
+	return permission, ok
+}
+
+// Match finds the route a request path belongs to, such as
+// "/api/v1/users/:id/block" for "/api/v1/users/42/block", and the permission
+// it requires. Like the router, it prefers static segments over parameters.
+func (p Permissions) Match(method, path string) (route, permission string, ok bool) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+
+	best := -1
+	for key, name := range p {
+		keyMethod, pattern, _ := strings.Cut(key, " ")
+		if keyMethod != method {
+			continue
+		}
+
+		score, matched := matchRoute(strings.Split(strings.Trim(pattern, "/"), "/"), segments)
+		if matched && (score > best || (score == best && pattern < route)) {
+			best, route, permission = score, pattern, name
+		}
+	}
+	return route, permission, best >= 0
+}
+
+// matchRoute matches path segments against the segments of a route pattern
+// and counts the static segments among them.
+func matchRoute(pattern, segments []string) (int, bool) {
+	static := 0
+	for i, part := range pattern {
+		if strings.HasPrefix(part, "*") {
+			return static, true
+		}
+		if i >= len(segments) {
+			return 0, false
+		}
+		if strings.HasPrefix(part, ":") {
+			continue
+		}
+		if part != segments[i] {
+			return 0, false
+		}
+		static++
+	}
+	return static, len(pattern) == len(segments)
+}
+
+// Routes returns the routes that require the permission as "METHOD path".
+func (p Permissions) Routes(permission string) []string {
+	routes := []string{}
+	for route, name := range p {
+		if name == permission {
+			routes = append(routes, route)
+		}
+	}
+	slices.Sort(routes)
+	return routes
+}
+
+// Authorizer checks the permission declared for the matched route against the
+// caller's roles in its tenant domain. Routes that declare none are denied.
+func Authorizer(log logger.Logger, e *casbin.SyncedEnforcer, permissions Permissions) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// The system role is not bound to a tenant domain and is allowed everywhere.
+		if c.GetString("userRole") == "system" {
+			c.Set("branchScope", model.BranchScope{Active: c.GetString("branchID"), All: true})
+			c.Next()
+			return
+		}
+
 		userID, exists := c.Get("userID")
 		if !exists {
-			response.Error(c, log, codes.AuthAccessTokenRequired, errors.New("user not authenticated"))
+			response.Error(c, log, codes.AuthRequired, errors.New("user not authenticated"))
 			return
 		}
 		sub := fmt.Sprintf("%v", userID)
 
-		dom, exists := c.Get("tenantID")
+		tenantID, exists := c.Get("tenantID")
 		if !exists {
-			response.Error(c, log, codes.AuthAccessTokenRequired, errors.New("tenant not found"))
+			response.Error(c, log, codes.TenantRequired, errors.New("tenant not found"))
+			return
+		}
+		dom := fmt.Sprintf("%v", tenantID)
+
+		name, ok := permissions.Get(c.Request.Method, c.FullPath())
+		if !ok {
+			response.Error(c, log, codes.PermissionDenied, errors.New("route declares no permission"))
+			return
+		}
+		// An API key is limited to its scopes on top of its account's roles.
+		if key, ok := c.Get("apiKey"); ok && !key.(model.APIKey).Allows(name) {
+			response.Error(c, log, codes.APIKeyScopeDenied, errors.New("permission is outside the api key scopes: "+name))
 			return
 		}
 
-		obj := c.Request.URL.Path
-		act := c.Request.Method
+		obj, act := permission.Split(name)
 
-		ok, err := e.Enforce(sub, dom, obj, act)
+		scope, err := branchScope(e, sub, dom, c.GetString("branchID"), obj, act)
 		if err != nil {
-			response.Error(c, log, codes.AuthAccessTokenRequired, errors.New("authorization error"))
+			response.Error(c, log, codes.InternalError, fmt.Errorf("authorization error: %w", err))
 			return
 		}
 
-		if !ok {
-			response.Error(c, log, codes.AuthAccessTokenRequired, errors.New("permission denied"))
+		if !scope.All && len(scope.IDs) == 0 {
+			response.Error(c, log, codes.PermissionDenied, errors.New("permission denied: "+name))
+			return
+		}
+
+		if scope.Active != "" && !scope.Allows(scope.Active) {
+			response.Error(c, log, codes.BranchAccessDenied, errors.New("permission denied in the active branch"))
 			return
 		}
 
+		c.Set("permission", name)
+		c.Set("branchScope", scope)
 		c.Next()
 	}
 }
+
+// branchScope finds where the caller holds the permission: in every branch
+// through a tenant-wide role, or in the branches where one of its branch roles
+// grants it. A caller limited to one branch works in it without choosing.
+func branchScope(e *casbin.SyncedEnforcer, sub, dom, active, obj, act string) (model.BranchScope, error) {
+	scope := model.BranchScope{Active: active}
+
+	ok, err := e.Enforce(sub, dom, "", obj, act)
+	if err != nil {
+		return scope, err
+	}
+	if ok {
+		scope.All = true
+		return scope, nil
+	}
+
+	rules, err := e.GetFilteredGroupingPolicy(0, sub)
+	if err != nil {
+		return scope, err
+	}
+
+	for _, rule := range rules {
+		branchID, ok := permission.BranchOf(dom, rule[2])
+		if !ok || slices.Contains(scope.IDs, branchID) {
+			continue
+		}
+
+		ok, err := e.Enforce(sub, dom, rule[2], obj, act)
+		if err != nil {
+			return scope, err
+		}
+		if ok {
+			scope.IDs = append(scope.IDs, branchID)
+		}
+	}
+
+	if scope.Active == "" && len(scope.IDs) == 1 {
+		scope.Active = scope.IDs[0]
+	}
+
+	return scope, nil
+}
+
+// BranchScope returns the branches the caller may act on, as set by
+// Authorizer. Handlers filter branch-owned data by it.
+func BranchScope(c *gin.Context) model.BranchScope {
+	scope, _ := c.Get("branchScope")
+	branchScope, _ := scope.(model.BranchScope)
+	return branchScope
+}
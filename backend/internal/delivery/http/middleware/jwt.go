@@ -4,6 +4,7 @@ import (
 	"errors"
 	"strings"
 
+	"github.com/asliddinberdiev/eirsystem/internal/model"
 	"github.com/asliddinberdiev/eirsystem/internal/service"
 	"github.com/asliddinberdiev/eirsystem/pkg/codes"
 	"github.com/asliddinberdiev/eirsystem/pkg/jwt"
@@ -12,11 +13,23 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// passwordChangePaths stay reachable while the user must change the password.
+var passwordChangePaths = map[string]bool{
+	"/api/v1/auth/password": true,
+}
+
+// NewJWTMiddleware authenticates the request with a user's access token or,
+// for machine integrations, with a service account API key sent in the
+// X-API-Key header or as the bearer token.
 func NewJWTMiddleware(log logger.Logger, jwt *jwt.Manager, svc *service.Service) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if apiKey := c.GetHeader("X-API-Key"); apiKey != "" {
+			authenticateAPIKey(c, log, svc, apiKey)
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			log.Error("STEP 1")
 			response.Error(c, log, codes.AuthAccessTokenRequired, errors.New("authorization header required"))
 			return
 		}
@@ -28,23 +41,128 @@ func NewJWTMiddleware(log logger.Logger, jwt *jwt.Manager, svc *service.Service)
 		}
 		accessToken := headerParts[1]
 
-		claims, err := jwt.ParseTokenUnverified(accessToken)
+		if strings.HasPrefix(accessToken, service.APIKeyPrefix) {
+			authenticateAPIKey(c, log, svc, accessToken)
+			return
+		}
+
+		claims, err := jwt.ValidateAccessToken(c.Request.Context(), accessToken)
 		if err != nil {
-			response.Error(c, log, codes.AuthTokenInvalid, err)
+			response.Error(c, log, tokenErrorCode(err), err)
 			return
 		}
 
-		user, err := svc.User.GetByID(claims.UserID)
+		user, err := svc.User.GetCachedByID(c.Request.Context(), claims.UserID)
 		if err != nil {
 			response.Error(c, log, codes.AuthTokenInvalid, err)
 			return
 		}
 
-		log.Info("User found", logger.Any("user", user))
+		if !user.IsActive {
+			response.Error(c, log, codes.UserInactive, errors.New("user account is inactive"))
+			return
+		}
+
+		if user.MustChangePassword && !passwordChangePaths[c.FullPath()] {
+			response.Error(c, log, codes.PasswordChangeRequired, errors.New("password must be changed before using the API"))
+			return
+		}
+
+		c.Set("userID", claims.UserID)
+		c.Set("userRole", claims.Role)
+		c.Set("tenantID", claims.TenantID)
+		c.Set("sessionID", claims.SessionID)
+		c.Set("branchID", claims.BranchID)
+
+		impersonatorID := claims.ImpersonatorID()
+		if impersonatorID == "" {
+			c.Next()
+			return
+		}
+
+		c.Set("impersonatorID", impersonatorID)
+		c.Header("X-Impersonated-By", impersonatorID)
+		c.Next()
+
+		svc.Impersonation.LogRequest(c.Request.Context(), model.ImpersonationRequest{
+			SessionID:      claims.SessionID,
+			UserID:         claims.UserID,
+			ImpersonatorID: impersonatorID,
+			Method:         c.Request.Method,
+			Path:           c.Request.URL.Path,
+			Status:         c.Writer.Status(),
+			RequestID:      c.GetString("requestID"),
+			ClientIP:       c.ClientIP(),
+		})
+	}
+}
+
+// authenticateAPIKey sets the service account as the caller, so Authorizer
+// checks its role like a user's, and rejects requests outside the key's scopes.
+func authenticateAPIKey(c *gin.Context, log logger.Logger, svc *service.Service, apiKey string) {
+	account, key, err := svc.ServiceAccount.Authenticate(c.Request.Context(), apiKey, c.ClientIP())
+	switch {
+	case errors.Is(err, service.ErrAPIKeyInvalid):
+		response.Error(c, log, codes.APIKeyInvalid, err)
+		return
+	case errors.Is(err, service.ErrAPIKeyExpired):
+		response.Error(c, log, codes.APIKeyExpired, err)
+		return
+	case err != nil:
+		response.Error(c, log, codes.InternalError, err)
+		return
+	}
+
+	c.Set("userID", account.ID)
+	c.Set("userRole", account.Role)
+	c.Set("tenantID", account.TenantID)
+	c.Set("apiKeyID", key.ID)
+	c.Set("apiKey", key)
+	c.Next()
+}
 
-		c.Set("userID", user.ID)
-		c.Set("userRole", user.Role)
-		c.Set("tenantID", user.TenantID)
+// UserOnly rejects service accounts and impersonation tokens on routes that
+// act on the caller's own user account, such as sessions, password and
+// two-factor settings.
+func UserOnly(log logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("apiKeyID") != "" {
+			response.Error(c, log, codes.APIKeyNotAllowed, errors.New("endpoint requires a user access token"))
+			return
+		}
+		if c.GetString("impersonatorID") != "" {
+			response.Error(c, log, codes.ImpersonationForbidden, errors.New("endpoint cannot be used while impersonating"))
+			return
+		}
 		c.Next()
 	}
 }
+
+// SystemOnly rejects every caller but the system role, on routes that manage
+// the platform rather than a tenant.
+func SystemOnly(log logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("userRole") != "system" {
+			response.Error(c, log, codes.PermissionDenied, errors.New("endpoint requires the system role"))
+			return
+		}
+		c.Next()
+	}
+}
+
+func tokenErrorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return codes.AuthTokenExpired
+	case errors.Is(err, jwt.ErrTokenInvalid):
+		return codes.AuthTokenInvalid
+	case errors.Is(err, jwt.ErrUserBlocked):
+		return codes.UserBlocked
+	case errors.Is(err, jwt.ErrSessionRevoked):
+		return codes.SessionRevoked
+	case errors.Is(err, jwt.ErrSessionMismatch):
+		return codes.SessionMismatch
+	default:
+		return codes.InternalError
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/asliddinberdiev/eirsystem/internal/model"
+	"github.com/asliddinberdiev/eirsystem/internal/service"
+	"github.com/asliddinberdiev/eirsystem/pkg/codes"
+	"github.com/asliddinberdiev/eirsystem/pkg/logger"
+	"github.com/asliddinberdiev/eirsystem/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// Subscription lets a tenant's users in while its subscription runs. For the
+// grace period after it ends they may only read, then they are blocked until
+// the system role renews it. System callers belong to no tenant and pass.
+func Subscription(log logger.Logger, svc *service.Service, grace time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID := c.GetString("tenantID")
+		if tenantID == "" {
+			c.Next()
+			return
+		}
+
+		subscription, err := svc.Tenant.GetSubscription(c.Request.Context(), tenantID)
+		if err != nil {
+			response.Error(c, log, codes.InternalError, fmt.Errorf("subscription lookup: %w", err))
+			return
+		}
+
+		state := subscription.State(time.Now(), grace)
+		c.Header("X-Subscription-State", state)
+
+		switch state {
+		case model.SubscriptionSuspended:
+			response.Error(c, log, codes.TenantInactive, errors.New("tenant is suspended"))
+			return
+		case model.SubscriptionExpired:
+			response.ErrorWithData(c, log, codes.SubscriptionExpired, errors.New("subscription expired"), subscription)
+			return
+		case model.SubscriptionGrace:
+			switch c.Request.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+			default:
+				response.ErrorWithData(c, log, codes.SubscriptionReadOnly, errors.New("subscription is in its grace period"), subscription)
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
@@ -2,6 +2,9 @@
 package v1
 
 import (
+	"net/http"
+	"path"
+
 	"github.com/asliddinberdiev/eirsystem/config"
 	_ "github.com/asliddinberdiev/eirsystem/docs"
 	"github.com/asliddinberdiev/eirsystem/internal/delivery/http/middleware"
@@ -22,7 +25,8 @@ type Handler struct {
 	valid    validator.Validator
 	jwt      *jwt.Manager
 	svc      *service.Service
-	enforcer *casbin.Enforcer
+	enforcer *casbin.SyncedEnforcer
+	perms    middleware.Permissions
 }
 
 // @title EIR System API
@@ -38,7 +42,7 @@ type Handler struct {
 // @in header
 // @name Authorization
 
-func NewHandler(cfg *config.Config, log logger.Logger, valid validator.Validator, jwt *jwt.Manager, svc *service.Service, enforcer *casbin.Enforcer) *Handler {
+func NewHandler(cfg *config.Config, log logger.Logger, valid validator.Validator, jwt *jwt.Manager, svc *service.Service, enforcer *casbin.SyncedEnforcer) *Handler {
 	return &Handler{
 		cfg:      cfg,
 		log:      log,
@@ -46,6 +50,7 @@ func NewHandler(cfg *config.Config, log logger.Logger, valid validator.Validator
 		jwt:      jwt,
 		svc:      svc,
 		enforcer: enforcer,
+		perms:    make(middleware.Permissions),
 	}
 }
 
@@ -63,14 +68,53 @@ func (h *Handler) Init(api *gin.RouterGroup) {
 	{
 		{
 			h.initAuthRoutes(v1)
+			h.initMeRoutes(v1)
+			h.initSystemRoutes(v1)
 
 			protected := v1.Group("")
 			protected.Use(middleware.NewJWTMiddleware(h.log, h.jwt, h.svc))
-			protected.Use(middleware.Authorizer(h.log.Named("MIDDLEWARE"), h.enforcer))
+			protected.Use(middleware.Subscription(h.log.Named("MIDDLEWARE"), h.svc, h.cfg.Subscription.GracePeriod))
+			protected.Use(middleware.Authorizer(h.log.Named("MIDDLEWARE"), h.enforcer, h.perms))
 			{
 				h.initUserRoutes(protected)
+				h.initTenantRoutes(protected)
+				h.initServiceAccountRoutes(protected)
+				h.initRoleRoutes(protected)
+				h.initAuthorizationRoutes(protected)
 				h.initTestRoutes(protected)
 			}
 		}
 	}
 }
+
+// permitted registers protected routes together with the permission each one
+// requires, which Authorizer enforces.
+type permitted struct {
+	group *gin.RouterGroup
+	perms middleware.Permissions
+}
+
+func (h *Handler) permit(group *gin.RouterGroup) permitted {
+	return permitted{group: group, perms: h.perms}
+}
+
+func (g permitted) GET(relativePath, permission string, handlers ...gin.HandlerFunc) {
+	g.handle(http.MethodGet, relativePath, permission, handlers)
+}
+
+func (g permitted) POST(relativePath, permission string, handlers ...gin.HandlerFunc) {
+	g.handle(http.MethodPost, relativePath, permission, handlers)
+}
+
+func (g permitted) PUT(relativePath, permission string, handlers ...gin.HandlerFunc) {
+	g.handle(http.MethodPut, relativePath, permission, handlers)
+}
+
+func (g permitted) DELETE(relativePath, permission string, handlers ...gin.HandlerFunc) {
+	g.handle(http.MethodDelete, relativePath, permission, handlers)
+}
+
+func (g permitted) handle(method, relativePath, permission string, handlers []gin.HandlerFunc) {
+	g.group.Handle(method, relativePath, handlers...)
+	g.perms.Set(method, path.Join(g.group.BasePath(), relativePath), permission)
+}
@@ -3,33 +3,50 @@ package v1
 
 import (
 	"errors"
+	"math"
+	"net"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/asliddinberdiev/eirsystem/internal/dto"
+	"github.com/asliddinberdiev/eirsystem/internal/model"
 	"github.com/asliddinberdiev/eirsystem/pkg/codes"
 	"github.com/asliddinberdiev/eirsystem/pkg/hasher"
+	"github.com/asliddinberdiev/eirsystem/pkg/jwt"
+	"github.com/asliddinberdiev/eirsystem/pkg/logger"
 	"github.com/asliddinberdiev/eirsystem/pkg/response"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 func (h *Handler) initAuthRoutes(api *gin.RouterGroup) {
 	auth := api.Group("/auth")
 	{
 		auth.POST("/sign-in", h.SignIn)
+		auth.POST("/system/sign-in", h.SystemSignIn)
 		auth.POST("/refresh", h.Refresh)
 		auth.POST("/logout", h.Logout)
 	}
+
+	h.initSessionRoutes(auth)
+	h.initPasswordRoutes(auth)
+	h.initTwoFactorRoutes(auth)
+	h.initImpersonationRoutes(auth)
+	h.initSSORoutes(auth)
 }
 
 // SignIn godoc
 // @Summary SignIn
-// @Description Foydalanuvchi tizimga kirishi va token olishi
+// @Description Klinika xodimi tizimga kirishi va token olishi. Klinika subdomen yoki tenant_slug orqali aniqlanadi
 // @Tags auth
 // @Accept  json
 // @Produce  json
 // @Param request body dto.SignInRequest true "SignIn Request"
 // @Response 200 {object} response.Response
 // @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 429 {object} response.Response
 // @Failure 500 {object} response.Response
 // @Router /auth/sign-in [post]
 func (h *Handler) SignIn(c *gin.Context) {
@@ -44,34 +61,215 @@ func (h *Handler) SignIn(c *gin.Context) {
 		return
 	}
 
-	user, err := h.svc.User.GetByUsername(req.Username)
+	tenant, ok := h.resolveTenant(c, req.TenantSlug)
+	if !ok {
+		return
+	}
+
+	h.signIn(c, tenant.ID, req.Username, req.Password, func() (model.User, error) {
+		return h.svc.User.GetByUsername(tenant.ID, req.Username)
+	})
+}
+
+// SystemSignIn godoc
+// @Summary System SignIn
+// @Description Tizim administratori klinikaga bog'lanmagan holda tizimga kirishi
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param request body dto.SystemSignInRequest true "SignIn Request"
+// @Response 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /auth/system/sign-in [post]
+func (h *Handler) SystemSignIn(c *gin.Context) {
+	var req dto.SystemSignInRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	if err := h.valid.Struct(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	h.signIn(c, "", req.Username, req.Password, func() (model.User, error) {
+		return h.svc.User.GetSystemByUsername(req.Username)
+	})
+}
+
+// resolveTenant finds the active tenant being signed in to, named by slug or
+// else by the subdomain of the request host.
+func (h *Handler) resolveTenant(c *gin.Context, slug string) (model.Tenant, bool) {
+	if slug == "" {
+		slug = tenantSlugFromHost(c.Request.Host, h.cfg.App.BaseDomain)
+	}
+	if slug == "" {
+		response.Error(c, h.log, codes.TenantRequired, errors.New("tenant could not be resolved from the host or tenant_slug"))
+		return model.Tenant{}, false
+	}
+
+	tenant, err := h.svc.Tenant.GetBySlug(slug)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		response.Error(c, h.log, codes.TenantNotFound, err)
+		return model.Tenant{}, false
+	}
+	if err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return model.Tenant{}, false
+	}
+	if !tenant.IsActive {
+		response.Error(c, h.log, codes.TenantInactive, errors.New("tenant is inactive"))
+		return model.Tenant{}, false
+	}
+
+	return tenant, true
+}
+
+// signIn checks the password of the user returned by lookup and either issues
+// tokens or starts the two-factor step. Failures are counted per tenant account,
+// and so are the failed codes of the two-factor step.
+func (h *Handler) signIn(c *gin.Context, tenantID, username, password string, lookup func() (model.User, error)) {
+	ctx := c.Request.Context()
+
+	lockout, err := h.svc.LoginAttempt.Check(ctx, tenantID, username, c.ClientIP())
 	if err != nil {
-		response.Error(c, h.log, codes.AuthInvalidCredentials, errors.New("username or password is incorrect"))
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+	if lockout > 0 {
+		h.lockedOut(c, lockout)
+		return
+	}
+
+	user, err := lookup()
+	if err != nil {
+		hasher.VerifyDummy(password)
+		h.signInFailed(c, tenantID, username)
+		return
+	}
+
+	if err := hasher.Verify(password, user.PasswordHash); err != nil {
+		h.signInFailed(c, tenantID, username)
+		return
+	}
+
+	if err := h.svc.Password.Rehash(ctx, user, password); err != nil {
+		h.log.Warn("password rehash failed", logger.String("user_id", user.ID), logger.Error(err))
+	}
+
+	if user.MustChangePassword && user.PasswordExpiresAt != nil && time.Now().After(*user.PasswordExpiresAt) {
+		response.Error(c, h.log, codes.TempPasswordExpired, errors.New("temporary password has expired, ask for a new one"))
+		return
+	}
+
+	if !h.ensureActive(c, user) {
 		return
 	}
 
-	if err := hasher.Verify(req.Password, user.PasswordHash); err != nil {
-		response.Error(c, h.log, codes.AuthInvalidCredentials, errors.New("username or password is incorrect"))
+	if h.startTwoFactor(c, user) {
 		return
 	}
-	accessToken, refreshToken, err := h.jwt.Generate(c.Request.Context(), user.ID, c.Request.UserAgent(), c.ClientIP())
+
+	// Failures are forgotten only once every factor has passed, so a known
+	// password does not buy fresh guesses at the second factor.
+	if err := h.svc.LoginAttempt.Reset(ctx, tenantID, username); err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	resp, err := h.issueTokens(c, user)
 	if err != nil {
 		response.Error(c, h.log, codes.InternalError, err)
 		return
 	}
 
-	resp := dto.SignInResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
+	response.Success(c, codes.Ok, resp)
+}
+
+// startTwoFactor sends the challenge of the two-factor step when the user has
+// 2FA enabled or its role requires it, and reports whether it answered.
+func (h *Handler) startTwoFactor(c *gin.Context, user model.User) bool {
+	if user.TOTPEnabled {
+		h.sendChallenge(c, user.ID, jwt.ChallengeTwoFactor, codes.TwoFactorRequired)
+		return true
+	}
+
+	required, err := h.svc.TwoFactor.IsRequired(user.TenantID, user.Role)
+	if err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return true
+	}
+	if required {
+		h.sendChallenge(c, user.ID, jwt.ChallengeTwoFactorEnroll, codes.TwoFactorSetupRequired)
+		return true
+	}
+
+	return false
+}
+
+// ensureActive lets a blocked user in only once the block has expired.
+func (h *Handler) ensureActive(c *gin.Context, user model.User) bool {
+	if user.IsActive {
+		return true
+	}
+
+	released, err := h.svc.UserBlock.ReleaseExpired(c.Request.Context(), user.ID)
+	if err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return false
+	}
+	if !released {
+		response.Error(c, h.log, codes.UserBlocked, errors.New("user account is blocked"))
+		return false
+	}
+	return true
+}
+
+// signInFailed counts the failed attempt and tells the client to wait when it
+// started a lockout of the username or of the client IP.
+func (h *Handler) signInFailed(c *gin.Context, tenantID, username string) {
+	lockout, err := h.svc.LoginAttempt.Fail(c.Request.Context(), tenantID, username, c.ClientIP())
+	if err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+	if lockout > 0 {
+		h.lockedOut(c, lockout)
+		return
+	}
+
+	response.Error(c, h.log, codes.AuthInvalidCredentials, errors.New("username or password is incorrect"))
+}
+
+func (h *Handler) lockedOut(c *gin.Context, lockout time.Duration) {
+	retryAfter := int(math.Ceil(lockout.Seconds()))
+
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	response.ErrorWithData(c, h.log, codes.AuthLockedOut, errors.New("sign-in is temporarily locked"), dto.LockoutResponse{
+		RetryAfter: retryAfter,
+	})
+}
+
+func (h *Handler) issueTokens(c *gin.Context, user model.User) (dto.SignInResponse, error) {
+	accessToken, refreshToken, err := h.jwt.Generate(c.Request.Context(), user.ID, user.Role, user.TenantID, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		return dto.SignInResponse{}, err
+	}
+
+	return dto.SignInResponse{
+		AccessToken:        accessToken,
+		RefreshToken:       refreshToken,
+		MustChangePassword: user.MustChangePassword,
 		User: dto.User{
 			ID:       user.ID,
 			Username: user.Username,
 			FullName: user.FullName,
 			Role:     user.Role,
 		},
-	}
-
-	response.Success(c, codes.Ok, resp)
+	}, nil
 }
 
 // Refresh godoc
@@ -116,9 +314,9 @@ func (h *Handler) Refresh(c *gin.Context) {
 		return
 	}
 
-	newAccess, newRefresh, err := h.jwt.Refresh(c.Request.Context(), claims.UserID, claims.SessionID, req.RefreshToken, c.Request.UserAgent())
+	newAccess, newRefresh, err := h.jwt.Refresh(c.Request.Context(), claims.UserID, claims.SessionID, req.RefreshToken, c.Request.UserAgent(), c.ClientIP())
 	if err != nil {
-		response.Error(c, h.log, codes.AuthTokenInvalid, err)
+		response.Error(c, h.log, refreshErrorCode(err), err)
 		return
 	}
 
@@ -128,6 +326,38 @@ func (h *Handler) Refresh(c *gin.Context) {
 	})
 }
 
+// tenantSlugFromHost returns the subdomain of host under baseDomain, so
+// "clinic.eirsystem.local" signs in to the tenant "clinic".
+func tenantSlugFromHost(host, baseDomain string) string {
+	if baseDomain == "" {
+		return ""
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	slug, ok := strings.CutSuffix(strings.ToLower(host), "."+strings.ToLower(baseDomain))
+	if !ok || slug == "" || strings.Contains(slug, ".") {
+		return ""
+	}
+	return slug
+}
+
+func refreshErrorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, jwt.ErrRefreshReused):
+		return codes.RefreshTokenReused
+	case errors.Is(err, jwt.ErrSessionDrift):
+		return codes.SessionContextChanged
+	case errors.Is(err, jwt.ErrSessionRevoked):
+		return codes.SessionRevoked
+	case errors.Is(err, jwt.ErrSessionMismatch):
+		return codes.SessionMismatch
+	default:
+		return codes.AuthTokenInvalid
+	}
+}
+
 // Logout godoc
 // @Summary Logout
 // @Description Tizimdan chiqish (Sessiyani o'chirish)
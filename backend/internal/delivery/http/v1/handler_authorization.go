@@ -0,0 +1,146 @@
+package v1
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/asliddinberdiev/eirsystem/internal/dto"
+	"github.com/asliddinberdiev/eirsystem/internal/service"
+	"github.com/asliddinberdiev/eirsystem/pkg/codes"
+	"github.com/asliddinberdiev/eirsystem/pkg/permission"
+	"github.com/asliddinberdiev/eirsystem/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) initAuthorizationRoutes(api *gin.RouterGroup) {
+	authorization := h.permit(api.Group("/authorization"))
+	{
+		authorization.POST("/explain", permission.RolesRead, h.ExplainAuthorization)
+		authorization.POST("/dry-run", permission.RolesManage, h.DryRunPolicyChange)
+	}
+}
+
+// ExplainAuthorization godoc
+// @Summary Explain authorization
+// @Description Tell whether a user may call a route, such as POST /api/v1/users/{id}/block, and which of its roles and policies grant it. The domain is the user's tenant, or one of its branches as the active branch when branch_id is set
+// @Tags authorization
+// @Accept  json
+// @Produce  json
+// @Param request body dto.ExplainRequest true "Explain Request"
+// @Response 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /authorization/explain [post]
+// @Security BearerAuth
+func (h *Handler) ExplainAuthorization(c *gin.Context) {
+	var req dto.ExplainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	if err := h.valid.Struct(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	path, _, _ := strings.Cut(req.Path, "?")
+	route, name, ok := h.perms.Match(req.Method, path)
+	if !ok {
+		response.Error(c, h.log, codes.InvalidRequest, errors.New("no protected route matches "+req.Method+" "+path))
+		return
+	}
+
+	user, err := h.svc.User.GetByID(req.UserID)
+	if err != nil || (!isSystem(c) && user.TenantID != c.GetString("tenantID")) {
+		response.Error(c, h.log, codes.UserNotFound, errors.New("user not found"))
+		return
+	}
+
+	var explanation service.Explanation
+	if user.Role == "system" {
+		explanation = service.Explanation{
+			Allowed:    true,
+			Permission: name,
+			Reason:     "the system role is allowed everywhere",
+			Roles:      []service.UserRole{{Role: "system"}},
+			Grants:     []service.Grant{},
+		}
+	} else {
+		explanation, err = h.svc.Policy.Explain(user.ID, user.TenantID, req.BranchID, name)
+		if err != nil {
+			response.Error(c, h.log, codes.InternalError, err)
+			return
+		}
+	}
+	explanation.Route = req.Method + " " + route
+
+	if !user.IsActive {
+		explanation.Allowed = false
+		explanation.Reason = "the user account is inactive; " + explanation.Reason
+	}
+
+	response.Success(c, codes.Ok, explanation)
+}
+
+// DryRunPolicyChange godoc
+// @Summary Dry-run policy change
+// @Description Show which users would gain or lose which permissions if the given permissions were granted to or revoked from roles, without changing anything. System callers choose the tenant with tenant_id
+// @Tags authorization
+// @Accept  json
+// @Produce  json
+// @Param request body dto.DryRunRequest true "Dry Run Request"
+// @Response 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /authorization/dry-run [post]
+// @Security BearerAuth
+func (h *Handler) DryRunPolicyChange(c *gin.Context) {
+	var req dto.DryRunRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	if err := h.valid.Struct(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	changes := make([]service.PolicyChange, 0, len(req.Changes))
+	for _, change := range req.Changes {
+		if !h.knownPermission(c, change.Permission) {
+			return
+		}
+		changes = append(changes, service.PolicyChange{
+			Grant:      change.Action == "grant",
+			Role:       change.Role,
+			Permission: change.Permission,
+		})
+	}
+
+	tenantID := req.TenantID
+	if !isSystem(c) {
+		var ok bool
+		if tenantID, ok = h.callerTenant(c); !ok {
+			return
+		}
+		if req.TenantID != "" && req.TenantID != tenantID {
+			response.Error(c, h.log, codes.TenantNotFound, errors.New("tenant not found"))
+			return
+		}
+	} else if tenantID == "" {
+		response.Error(c, h.log, codes.TenantRequired, errors.New("tenant_id is required for system callers"))
+		return
+	}
+
+	result, err := h.svc.Policy.DryRun(tenantID, changes, slices.Clone(permission.Known))
+	if err != nil {
+		response.Error(c, h.log, roleErrorCode(err), err)
+		return
+	}
+
+	response.Success(c, codes.Ok, result)
+}
@@ -0,0 +1,136 @@
+package v1
+
+import (
+	"errors"
+
+	"github.com/asliddinberdiev/eirsystem/internal/delivery/http/middleware"
+	"github.com/asliddinberdiev/eirsystem/internal/dto"
+	"github.com/asliddinberdiev/eirsystem/internal/model"
+	"github.com/asliddinberdiev/eirsystem/pkg/codes"
+	"github.com/asliddinberdiev/eirsystem/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) initImpersonationRoutes(auth *gin.RouterGroup) {
+	impersonation := auth.Group("/impersonation-consent")
+	impersonation.Use(middleware.NewJWTMiddleware(h.log, h.jwt, h.svc))
+	impersonation.Use(middleware.UserOnly(h.log))
+	{
+		impersonation.PUT("", h.SetImpersonationConsent)
+	}
+}
+
+// ImpersonateUser godoc
+// @Summary Impersonate user
+// @Description Issue a short-lived, non-refreshable access token that acts as a tenant user on behalf of the calling system administrator. Owners can only be impersonated after opting in. Every request made with the token is audited
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param id path string true "User ID"
+// @Param request body dto.ImpersonateRequest true "Impersonate Request"
+// @Response 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /users/{id}/impersonate [post]
+// @Security BearerAuth
+func (h *Handler) ImpersonateUser(c *gin.Context) {
+	var req dto.ImpersonateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	if err := h.valid.Struct(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	if !isSystem(c) || c.GetString("apiKeyID") != "" {
+		response.Error(c, h.log, codes.UserActionForbidden, errors.New("only system administrators can impersonate users"))
+		return
+	}
+
+	user, err := h.svc.User.GetByID(c.Param("id"))
+	if err != nil {
+		response.Error(c, h.log, codes.UserNotFound, errors.New("user not found"))
+		return
+	}
+
+	switch {
+	case user.Role == "system":
+		response.Error(c, h.log, codes.UserActionForbidden, errors.New("system users cannot be impersonated"))
+		return
+	case user.Role == "owner" && !user.AllowImpersonation:
+		response.Error(c, h.log, codes.ImpersonationNotAllowed, errors.New("owner has not allowed impersonation"))
+		return
+	case !user.IsActive:
+		response.Error(c, h.log, codes.UserInactive, errors.New("user account is inactive"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	impersonatorID := c.GetString("userID")
+
+	token, err := h.jwt.Impersonate(ctx, user.ID, user.Role, user.TenantID, impersonatorID, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	err = h.svc.Impersonation.Start(ctx, &model.Impersonation{
+		TenantID:       nullableString(user.TenantID),
+		UserID:         user.ID,
+		ImpersonatorID: impersonatorID,
+		SessionID:      token.SessionID,
+		Reason:         req.Reason,
+		ClientIP:       c.ClientIP(),
+		UserAgent:      c.Request.UserAgent(),
+		ExpiresAt:      token.ExpiresAt,
+	})
+	if err != nil {
+		if revokeErr := h.jwt.RevokeSession(ctx, user.ID, token.SessionID); revokeErr != nil {
+			err = errors.Join(err, revokeErr)
+		}
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	response.Success(c, codes.Ok, dto.ImpersonationResponse{
+		AccessToken: token.AccessToken,
+		SessionID:   token.SessionID,
+		ExpiresAt:   token.ExpiresAt,
+		User: dto.User{
+			ID:       user.ID,
+			Username: user.Username,
+			FullName: user.FullName,
+			Role:     user.Role,
+		},
+	})
+}
+
+// SetImpersonationConsent godoc
+// @Summary Set impersonation consent
+// @Description Tizim administratoriga hisobingiz nomidan ishlashga ruxsat berish yoki ruxsatni bekor qilish (faqat owner uchun talab qilinadi)
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param request body dto.ImpersonationConsentRequest true "Impersonation Consent Request"
+// @Response 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /auth/impersonation-consent [put]
+// @Security BearerAuth
+func (h *Handler) SetImpersonationConsent(c *gin.Context) {
+	var req dto.ImpersonationConsentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	if err := h.svc.Impersonation.SetConsent(c.Request.Context(), c.GetString("userID"), req.Allow); err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	response.Success(c, codes.Ok, nil)
+}
@@ -0,0 +1,60 @@
+package v1
+
+import (
+	"slices"
+
+	"github.com/asliddinberdiev/eirsystem/internal/delivery/http/middleware"
+	"github.com/asliddinberdiev/eirsystem/internal/service"
+	"github.com/asliddinberdiev/eirsystem/pkg/codes"
+	"github.com/asliddinberdiev/eirsystem/pkg/permission"
+	"github.com/asliddinberdiev/eirsystem/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// initMeRoutes registers the routes that describe the caller. They need no
+// permission, so they sit outside Authorizer.
+func (h *Handler) initMeRoutes(api *gin.RouterGroup) {
+	me := api.Group("/me")
+	me.Use(middleware.NewJWTMiddleware(h.log, h.jwt, h.svc))
+	{
+		me.GET("/permissions", h.GetMyPermissions)
+	}
+}
+
+// GetMyPermissions godoc
+// @Summary Get my permissions
+// @Description Fetch the caller's roles, the permissions it holds tenant-wide and the branches it can work in with the permissions it holds in each. The response carries an ETag; send it back in If-None-Match to get 304 until the caller's roles or policies change
+// @Tags me
+// @Produce  json
+// @Param If-None-Match header string false "ETag of the cached response"
+// @Response 200 {object} response.Response
+// @Response 304 {string} string "Not Modified"
+// @Failure 401 {object} response.Response
+// @Router /me/permissions [get]
+// @Security BearerAuth
+func (h *Handler) GetMyPermissions(c *gin.Context) {
+	names := slices.Clone(permission.Known)
+
+	// The system role is allowed everywhere and has no branches.
+	if isSystem(c) {
+		response.SuccessWithETag(c, codes.Ok, service.EffectivePermissions{
+			Roles:       []service.UserRole{{Role: "system"}},
+			Permissions: names,
+			Branches:    []service.BranchPermissions{},
+		})
+		return
+	}
+
+	tenantID, ok := h.callerTenant(c)
+	if !ok {
+		return
+	}
+
+	permissions, err := h.svc.Policy.GetEffectivePermissions(c.GetString("userID"), tenantID, names)
+	if err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	response.SuccessWithETag(c, codes.Ok, permissions)
+}
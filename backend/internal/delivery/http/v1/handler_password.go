@@ -0,0 +1,211 @@
+package v1
+
+import (
+	"errors"
+
+	"github.com/asliddinberdiev/eirsystem/internal/delivery/http/middleware"
+	"github.com/asliddinberdiev/eirsystem/internal/dto"
+	"github.com/asliddinberdiev/eirsystem/internal/model"
+	"github.com/asliddinberdiev/eirsystem/internal/service"
+	"github.com/asliddinberdiev/eirsystem/pkg/codes"
+	"github.com/asliddinberdiev/eirsystem/pkg/response"
+	"github.com/asliddinberdiev/eirsystem/pkg/validator"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) initPasswordRoutes(auth *gin.RouterGroup) {
+	password := auth.Group("/password")
+	password.Use(middleware.NewJWTMiddleware(h.log, h.jwt, h.svc))
+	password.Use(middleware.UserOnly(h.log))
+	{
+		password.POST("", h.ChangePassword)
+	}
+}
+
+// ChangePassword godoc
+// @Summary Change password
+// @Description Joriy parolni tekshirib yangi parol o'rnatish, boshqa sessiyalar yopiladi
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param request body dto.ChangePasswordRequest true "Change Password Request"
+// @Response 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Router /auth/password [post]
+// @Security BearerAuth
+func (h *Handler) ChangePassword(c *gin.Context) {
+	var req dto.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	if err := h.valid.Struct(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	userID := c.GetString("userID")
+	user, err := h.svc.User.GetByID(userID)
+	if err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	// Wrong current passwords count toward the sign-in lockout, so a stolen
+	// access token cannot be used to guess the password.
+	lockout, err := h.svc.LoginAttempt.Check(c.Request.Context(), user.TenantID, user.Username, c.ClientIP())
+	if err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+	if lockout > 0 {
+		h.lockedOut(c, lockout)
+		return
+	}
+
+	err = h.svc.Password.Change(c.Request.Context(), userID, req.CurrentPassword, req.NewPassword)
+
+	var policyErr *validator.Error
+	switch {
+	case errors.As(err, &policyErr):
+		response.ErrorWithData(c, h.log, codes.PasswordPolicyWeak, err, dto.PasswordPolicyViolationResponse{
+			Violations: policyErr.Messages,
+		})
+		return
+	case errors.Is(err, service.ErrPasswordWrong):
+		h.passwordWrong(c, user, err)
+		return
+	case errors.Is(err, service.ErrPasswordReused):
+		response.Error(c, h.log, codes.PasswordReused, err)
+		return
+	case err != nil:
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	if err := h.jwt.LogoutOthers(c.Request.Context(), userID, c.GetString("sessionID")); err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	response.Success(c, codes.Ok, nil)
+}
+
+func (h *Handler) passwordWrong(c *gin.Context, user model.User, err error) {
+	lockout, failErr := h.svc.LoginAttempt.Fail(c.Request.Context(), user.TenantID, user.Username, c.ClientIP())
+	if failErr != nil {
+		response.Error(c, h.log, codes.InternalError, failErr)
+		return
+	}
+	if lockout > 0 {
+		h.lockedOut(c, lockout)
+		return
+	}
+
+	response.Error(c, h.log, codes.UserPasswordWrong, err)
+}
+
+// ResetUserPassword godoc
+// @Summary Reset user password
+// @Description Replace the password of a staff account with a one-time temporary password that must be changed at the next sign-in, and revoke all of its sessions
+// @Tags users
+// @Produce  json
+// @Param id path string true "User ID"
+// @Response 200 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /users/{id}/password/reset [post]
+// @Security BearerAuth
+func (h *Handler) ResetUserPassword(c *gin.Context) {
+	user, ok := h.getManagedUser(c)
+	if !ok {
+		return
+	}
+
+	temporary, err := h.svc.Password.Reset(c.Request.Context(), user.ID)
+	if err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	if err := h.jwt.LogoutAll(c.Request.Context(), user.ID); err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	response.Success(c, codes.Ok, dto.TemporaryPasswordResponse{
+		TemporaryPassword: temporary.Password,
+		ExpiresAt:         temporary.ExpiresAt,
+	})
+}
+
+// GetPasswordPolicy godoc
+// @Summary Get password policy
+// @Description Fetch the password policy of the caller's tenant
+// @Tags tenant
+// @Produce  json
+// @Response 200 {object} response.Response
+// @Router /tenant/password-policy [get]
+// @Security BearerAuth
+func (h *Handler) GetPasswordPolicy(c *gin.Context) {
+	tenantID, ok := h.callerTenant(c)
+	if !ok {
+		return
+	}
+
+	policy, err := h.svc.Password.GetPolicy(tenantID)
+	if err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	response.Success(c, codes.Ok, policy)
+}
+
+// SetPasswordPolicy godoc
+// @Summary Set password policy
+// @Description Replace the password policy of the caller's tenant; it applies to passwords set from now on
+// @Tags tenant
+// @Accept  json
+// @Produce  json
+// @Param request body dto.PasswordPolicyRequest true "Password Policy Request"
+// @Response 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /tenant/password-policy [put]
+// @Security BearerAuth
+func (h *Handler) SetPasswordPolicy(c *gin.Context) {
+	var req dto.PasswordPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	if err := h.valid.Struct(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	tenantID, ok := h.callerTenant(c)
+	if !ok {
+		return
+	}
+
+	policy := &model.TenantPasswordPolicy{
+		TenantID:      tenantID,
+		MinLength:     req.MinLength,
+		RequireUpper:  req.RequireUpper,
+		RequireLower:  req.RequireLower,
+		RequireDigit:  req.RequireDigit,
+		RequireSymbol: req.RequireSymbol,
+		HistorySize:   req.HistorySize,
+	}
+
+	if err := h.svc.Password.SetPolicy(policy); err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	response.Success(c, codes.Ok, policy)
+}
@@ -0,0 +1,357 @@
+package v1
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/asliddinberdiev/eirsystem/internal/dto"
+	"github.com/asliddinberdiev/eirsystem/internal/model"
+	"github.com/asliddinberdiev/eirsystem/internal/service"
+	"github.com/asliddinberdiev/eirsystem/pkg/codes"
+	"github.com/asliddinberdiev/eirsystem/pkg/permission"
+	"github.com/asliddinberdiev/eirsystem/pkg/response"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func (h *Handler) initRoleRoutes(api *gin.RouterGroup) {
+	roles := h.permit(api.Group("/roles"))
+	{
+		roles.GET("", permission.RolesRead, h.GetRoles)
+		roles.POST("", permission.RolesManage, h.CreateRole)
+		roles.DELETE("/:role", permission.RolesManage, h.DeleteRole)
+		roles.POST("/:role/permissions", permission.RolesManage, h.GrantPermission)
+		roles.DELETE("/:role/permissions/:permission", permission.RolesManage, h.RevokePermission)
+	}
+
+	h.permit(api).GET("/permissions", permission.RolesRead, h.GetPermissions)
+
+	users := h.permit(api.Group("/users/:id/roles"))
+	{
+		users.GET("", permission.RolesRead, h.GetUserRoles)
+		users.POST("", permission.RolesAssign, h.AssignRole)
+		users.DELETE("/:role", permission.RolesAssign, h.UnassignRole)
+	}
+}
+
+// GetRoles godoc
+// @Summary Get roles
+// @Description Fetch the built-in and custom roles of the caller's tenant with the permissions granted to each
+// @Tags roles
+// @Produce  json
+// @Response 200 {object} response.Response
+// @Router /roles [get]
+// @Security BearerAuth
+func (h *Handler) GetRoles(c *gin.Context) {
+	tenantID, ok := h.callerTenant(c)
+	if !ok {
+		return
+	}
+
+	roles, err := h.svc.Policy.GetRoles(tenantID)
+	if err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	response.Success(c, codes.Ok, roles)
+}
+
+// CreateRole godoc
+// @Summary Create role
+// @Description Create a custom role in the caller's tenant; it has no permissions until they are granted
+// @Tags roles
+// @Accept  json
+// @Produce  json
+// @Param request body dto.CreateRoleRequest true "Role Request"
+// @Response 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /roles [post]
+// @Security BearerAuth
+func (h *Handler) CreateRole(c *gin.Context) {
+	var req dto.CreateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	if err := h.valid.Struct(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	tenantID, ok := h.callerTenant(c)
+	if !ok {
+		return
+	}
+
+	role := &model.TenantRole{
+		TenantID:    tenantID,
+		Name:        req.Name,
+		Description: req.Description,
+		CreatedBy:   actorID(c),
+	}
+
+	if err := h.svc.Policy.CreateRole(role); err != nil {
+		response.Error(c, h.log, roleErrorCode(err), err)
+		return
+	}
+
+	response.Success(c, codes.Ok, role)
+}
+
+// DeleteRole godoc
+// @Summary Delete role
+// @Description Delete a custom role together with its permissions and assignments; built-in roles cannot be deleted
+// @Tags roles
+// @Produce  json
+// @Param role path string true "Role name"
+// @Response 200 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /roles/{role} [delete]
+// @Security BearerAuth
+func (h *Handler) DeleteRole(c *gin.Context) {
+	tenantID, ok := h.callerTenant(c)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.Policy.DeleteRole(tenantID, c.Param("role")); err != nil {
+		response.Error(c, h.log, roleErrorCode(err), err)
+		return
+	}
+
+	response.Success(c, codes.Ok, nil)
+}
+
+// GrantPermission godoc
+// @Summary Grant permission
+// @Description Grant a permission to a role; the permission must be one listed by GET /permissions
+// @Tags roles
+// @Accept  json
+// @Produce  json
+// @Param role path string true "Role name"
+// @Param request body dto.GrantPermissionRequest true "Permission Request"
+// @Response 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /roles/{role}/permissions [post]
+// @Security BearerAuth
+func (h *Handler) GrantPermission(c *gin.Context) {
+	var req dto.GrantPermissionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	if err := h.valid.Struct(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	if !h.knownPermission(c, req.Permission) {
+		return
+	}
+
+	tenantID, ok := h.callerTenant(c)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.Policy.GrantPermission(tenantID, c.Param("role"), req.Permission); err != nil {
+		response.Error(c, h.log, roleErrorCode(err), err)
+		return
+	}
+
+	response.Success(c, codes.Ok, nil)
+}
+
+// RevokePermission godoc
+// @Summary Revoke permission
+// @Description Take a permission away from a role
+// @Tags roles
+// @Produce  json
+// @Param role path string true "Role name"
+// @Param permission path string true "Permission, e.g. users:block"
+// @Response 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /roles/{role}/permissions/{permission} [delete]
+// @Security BearerAuth
+func (h *Handler) RevokePermission(c *gin.Context) {
+	name := c.Param("permission")
+	if !h.knownPermission(c, name) {
+		return
+	}
+
+	tenantID, ok := h.callerTenant(c)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.Policy.RevokePermission(tenantID, c.Param("role"), name); err != nil {
+		response.Error(c, h.log, roleErrorCode(err), err)
+		return
+	}
+
+	response.Success(c, codes.Ok, nil)
+}
+
+// GetPermissions godoc
+// @Summary Get permissions
+// @Description Fetch every permission that can be granted with the routes that require it; permissions of resources without routes yet have none
+// @Tags roles
+// @Produce  json
+// @Response 200 {object} response.Response
+// @Router /permissions [get]
+// @Security BearerAuth
+func (h *Handler) GetPermissions(c *gin.Context) {
+	names := slices.Clone(permission.Known)
+
+	permissions := make([]dto.Permission, 0, len(names))
+	for _, name := range names {
+		permissions = append(permissions, dto.Permission{Name: name, Routes: h.perms.Routes(name)})
+	}
+
+	response.Success(c, codes.Ok, permissions)
+}
+
+// GetUserRoles godoc
+// @Summary Get user roles
+// @Description Fetch the roles assigned to a user in the caller's tenant; branch roles carry their branch_id
+// @Tags roles
+// @Produce  json
+// @Param id path string true "User ID"
+// @Response 200 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /users/{id}/roles [get]
+// @Security BearerAuth
+func (h *Handler) GetUserRoles(c *gin.Context) {
+	user, ok := h.getManagedUser(c)
+	if !ok {
+		return
+	}
+
+	roles, err := h.svc.Policy.GetUserRoles(user.ID, user.TenantID)
+	if err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	response.Success(c, codes.Ok, roles)
+}
+
+// AssignRole godoc
+// @Summary Assign role
+// @Description Give a user an additional role in the caller's tenant, or only in one of its branches when branch_id is set
+// @Tags roles
+// @Accept  json
+// @Produce  json
+// @Param id path string true "User ID"
+// @Param request body dto.AssignRoleRequest true "Assign Role Request"
+// @Response 200 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /users/{id}/roles [post]
+// @Security BearerAuth
+func (h *Handler) AssignRole(c *gin.Context) {
+	var req dto.AssignRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	if err := h.valid.Struct(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	user, ok := h.getManagedUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.Policy.AssignRole(user.ID, req.Role, user.TenantID, req.BranchID); err != nil {
+		response.Error(c, h.log, roleErrorCode(err), err)
+		return
+	}
+
+	response.Success(c, codes.Ok, nil)
+}
+
+// UnassignRole godoc
+// @Summary Unassign role
+// @Description Take an additional role away from a user, tenant-wide or in the branch given by branch_id; the user's primary role stays
+// @Tags roles
+// @Produce  json
+// @Param id path string true "User ID"
+// @Param role path string true "Role name"
+// @Param branch_id query string false "Branch ID"
+// @Response 200 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /users/{id}/roles/{role} [delete]
+// @Security BearerAuth
+func (h *Handler) UnassignRole(c *gin.Context) {
+	user, ok := h.getManagedUser(c)
+	if !ok {
+		return
+	}
+
+	role := c.Param("role")
+	branchID := c.Query("branch_id")
+	if branchID != "" {
+		if err := uuid.Validate(branchID); err != nil {
+			response.Error(c, h.log, codes.InvalidRequest, err)
+			return
+		}
+	} else if role == user.Role {
+		response.Error(c, h.log, codes.RoleProtected, errors.New("the user's primary role cannot be unassigned"))
+		return
+	}
+
+	if err := h.svc.Policy.UnassignRole(user.ID, role, user.TenantID, branchID); err != nil {
+		response.Error(c, h.log, roleErrorCode(err), err)
+		return
+	}
+
+	response.Success(c, codes.Ok, nil)
+}
+
+func (h *Handler) callerTenant(c *gin.Context) (string, bool) {
+	tenantID := c.GetString("tenantID")
+	if tenantID == "" {
+		response.Error(c, h.log, codes.InvalidRequest, errors.New("caller is not bound to a tenant"))
+		return "", false
+	}
+	return tenantID, true
+}
+
+// knownPermission checks that the permission is in the registry, or that it
+// is a wildcard over known ones.
+func (h *Handler) knownPermission(c *gin.Context, name string) bool {
+	if permission.IsKnown(name) {
+		return true
+	}
+
+	response.Error(c, h.log, codes.PermissionUnknown, errors.New("unknown permission "+name))
+	return false
+}
+
+func roleErrorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, service.ErrRoleNotFound):
+		return codes.RoleNotFound
+	case errors.Is(err, service.ErrRoleExists):
+		return codes.RoleExists
+	case errors.Is(err, service.ErrRoleProtected):
+		return codes.RoleProtected
+	case errors.Is(err, service.ErrBranchNotFound):
+		return codes.BranchNotFound
+	default:
+		return codes.InternalError
+	}
+}
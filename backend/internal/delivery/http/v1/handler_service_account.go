@@ -0,0 +1,321 @@
+package v1
+
+import (
+	"errors"
+	"time"
+
+	"github.com/asliddinberdiev/eirsystem/internal/dto"
+	"github.com/asliddinberdiev/eirsystem/internal/model"
+	"github.com/asliddinberdiev/eirsystem/internal/service"
+	"github.com/asliddinberdiev/eirsystem/pkg/codes"
+	"github.com/asliddinberdiev/eirsystem/pkg/permission"
+	"github.com/asliddinberdiev/eirsystem/pkg/response"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (h *Handler) initServiceAccountRoutes(api *gin.RouterGroup) {
+	accounts := h.permit(api.Group("/service-accounts"))
+	{
+		accounts.GET("", permission.ServiceAccountsRead, h.GetServiceAccounts)
+		accounts.POST("", permission.ServiceAccountsManage, h.CreateServiceAccount)
+		accounts.DELETE("/:id", permission.ServiceAccountsManage, h.DeleteServiceAccount)
+		accounts.GET("/:id/keys", permission.ServiceAccountsRead, h.GetAPIKeys)
+		accounts.POST("/:id/keys", permission.ServiceAccountsManage, h.CreateAPIKey)
+		accounts.POST("/:id/keys/:keyId/rotate", permission.ServiceAccountsManage, h.RotateAPIKey)
+		accounts.DELETE("/:id/keys/:keyId", permission.ServiceAccountsManage, h.RevokeAPIKey)
+	}
+}
+
+// GetServiceAccounts godoc
+// @Summary Get service accounts
+// @Description Fetch the service accounts of the caller's tenant
+// @Tags service-accounts
+// @Produce  json
+// @Response 200 {object} response.Response
+// @Router /service-accounts [get]
+// @Security BearerAuth
+func (h *Handler) GetServiceAccounts(c *gin.Context) {
+	tenantID, ok := h.callerTenant(c)
+	if !ok {
+		return
+	}
+
+	accounts, err := h.svc.ServiceAccount.GetAll(tenantID)
+	if err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	response.Success(c, codes.Ok, accounts)
+}
+
+// CreateServiceAccount godoc
+// @Summary Create service account
+// @Description Create a tenant-scoped service account for a machine integration; it is authorized with the permissions of its role
+// @Tags service-accounts
+// @Accept  json
+// @Produce  json
+// @Param request body dto.CreateServiceAccountRequest true "Service Account Request"
+// @Response 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /service-accounts [post]
+// @Security BearerAuth
+func (h *Handler) CreateServiceAccount(c *gin.Context) {
+	var req dto.CreateServiceAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	if err := h.valid.Struct(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	tenantID, ok := h.callerTenant(c)
+	if !ok {
+		return
+	}
+
+	account := &model.ServiceAccount{
+		ID:          uuid.New().String(),
+		TenantID:    tenantID,
+		Name:        req.Name,
+		Description: req.Description,
+		Role:        req.Role,
+		IsActive:    true,
+		CreatedBy:   actorID(c),
+	}
+
+	err := h.svc.ServiceAccount.Create(account)
+	if errors.Is(err, service.ErrServiceAccountExists) {
+		response.Error(c, h.log, codes.ServiceAccountExists, err)
+		return
+	}
+	if err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	if err := h.svc.Policy.AddRoleToUser(account.ID, account.Role, tenantID); err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	response.Success(c, codes.Ok, account)
+}
+
+// DeleteServiceAccount godoc
+// @Summary Delete service account
+// @Description Delete a service account together with all of its API keys
+// @Tags service-accounts
+// @Produce  json
+// @Param id path string true "Service Account ID"
+// @Response 200 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /service-accounts/{id} [delete]
+// @Security BearerAuth
+func (h *Handler) DeleteServiceAccount(c *gin.Context) {
+	tenantID, ok := h.callerTenant(c)
+	if !ok {
+		return
+	}
+
+	err := h.svc.ServiceAccount.Delete(tenantID, c.Param("id"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		response.Error(c, h.log, codes.ServiceAccountNotFound, err)
+		return
+	}
+	if err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	if err := h.svc.Policy.RemoveUser(c.Param("id"), tenantID); err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	response.Success(c, codes.Ok, nil)
+}
+
+// GetAPIKeys godoc
+// @Summary Get API keys
+// @Description Fetch the API keys of a service account with their scopes, expiry and last use
+// @Tags service-accounts
+// @Produce  json
+// @Param id path string true "Service Account ID"
+// @Response 200 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /service-accounts/{id}/keys [get]
+// @Security BearerAuth
+func (h *Handler) GetAPIKeys(c *gin.Context) {
+	account, ok := h.getServiceAccount(c)
+	if !ok {
+		return
+	}
+
+	keys, err := h.svc.ServiceAccount.GetKeys(account.ID)
+	if err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	response.Success(c, codes.Ok, keys)
+}
+
+// CreateAPIKey godoc
+// @Summary Create API key
+// @Description Issue an API key for a service account. Scopes are permission names such as patients:read, listed by GET /permissions, and may use "*" like a policy; the key is shown only once
+// @Tags service-accounts
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Service Account ID"
+// @Param request body dto.CreateAPIKeyRequest true "API Key Request"
+// @Response 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /service-accounts/{id}/keys [post]
+// @Security BearerAuth
+func (h *Handler) CreateAPIKey(c *gin.Context) {
+	var req dto.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	if err := h.valid.Struct(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	for _, scope := range req.Scopes {
+		if !h.knownPermission(c, scope) {
+			return
+		}
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		response.Error(c, h.log, codes.InvalidRequest, errors.New("expires_at must be in the future"))
+		return
+	}
+
+	account, ok := h.getServiceAccount(c)
+	if !ok {
+		return
+	}
+
+	key := &model.APIKey{
+		TenantID:         account.TenantID,
+		ServiceAccountID: account.ID,
+		Name:             req.Name,
+		Scopes:           req.Scopes,
+		ExpiresAt:        req.ExpiresAt,
+		CreatedBy:        actorID(c),
+	}
+
+	rawKey, err := h.svc.ServiceAccount.CreateKey(key)
+	if err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	response.Success(c, codes.Ok, apiKeyCreated(*key, rawKey))
+}
+
+// RotateAPIKey godoc
+// @Summary Rotate API key
+// @Description Issue a replacement for an API key; the old key keeps working for 24 hours
+// @Tags service-accounts
+// @Produce  json
+// @Param id path string true "Service Account ID"
+// @Param keyId path string true "API Key ID"
+// @Response 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /service-accounts/{id}/keys/{keyId}/rotate [post]
+// @Security BearerAuth
+func (h *Handler) RotateAPIKey(c *gin.Context) {
+	account, ok := h.getServiceAccount(c)
+	if !ok {
+		return
+	}
+
+	key, rawKey, err := h.svc.ServiceAccount.RotateKey(account.ID, c.Param("keyId"), actorID(c))
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.Error(c, h.log, codes.APIKeyNotFound, err)
+		return
+	case errors.Is(err, service.ErrAPIKeyExpired):
+		response.Error(c, h.log, codes.APIKeyExpired, err)
+		return
+	case err != nil:
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	response.Success(c, codes.Ok, apiKeyCreated(key, rawKey))
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke API key
+// @Description Revoke an API key immediately
+// @Tags service-accounts
+// @Produce  json
+// @Param id path string true "Service Account ID"
+// @Param keyId path string true "API Key ID"
+// @Response 200 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /service-accounts/{id}/keys/{keyId} [delete]
+// @Security BearerAuth
+func (h *Handler) RevokeAPIKey(c *gin.Context) {
+	account, ok := h.getServiceAccount(c)
+	if !ok {
+		return
+	}
+
+	err := h.svc.ServiceAccount.RevokeKey(account.ID, c.Param("keyId"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		response.Error(c, h.log, codes.APIKeyNotFound, err)
+		return
+	}
+	if err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	response.Success(c, codes.Ok, nil)
+}
+
+// getServiceAccount loads the service account from the :id path parameter
+// within the caller's tenant.
+func (h *Handler) getServiceAccount(c *gin.Context) (model.ServiceAccount, bool) {
+	tenantID, ok := h.callerTenant(c)
+	if !ok {
+		return model.ServiceAccount{}, false
+	}
+
+	account, err := h.svc.ServiceAccount.GetByID(tenantID, c.Param("id"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		response.Error(c, h.log, codes.ServiceAccountNotFound, err)
+		return model.ServiceAccount{}, false
+	}
+	if err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return model.ServiceAccount{}, false
+	}
+	return account, true
+}
+
+func apiKeyCreated(key model.APIKey, rawKey string) dto.APIKeyCreatedResponse {
+	return dto.APIKeyCreatedResponse{
+		ID:        key.ID,
+		Name:      key.Name,
+		Key:       rawKey,
+		Prefix:    key.Prefix,
+		Scopes:    key.Scopes,
+		ExpiresAt: key.ExpiresAt,
+	}
+}
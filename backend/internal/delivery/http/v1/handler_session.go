@@ -0,0 +1,184 @@
+package v1
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/asliddinberdiev/eirsystem/internal/delivery/http/middleware"
+	"github.com/asliddinberdiev/eirsystem/internal/dto"
+	"github.com/asliddinberdiev/eirsystem/internal/model"
+	"github.com/asliddinberdiev/eirsystem/pkg/codes"
+	"github.com/asliddinberdiev/eirsystem/pkg/jwt"
+	"github.com/asliddinberdiev/eirsystem/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) initSessionRoutes(auth *gin.RouterGroup) {
+	sessions := auth.Group("/sessions")
+	sessions.Use(middleware.NewJWTMiddleware(h.log, h.jwt, h.svc))
+	sessions.Use(middleware.UserOnly(h.log))
+	{
+		sessions.GET("", h.GetSessions)
+		sessions.DELETE("", h.RevokeOtherSessions)
+		sessions.DELETE("/:id", h.RevokeSession)
+		sessions.GET("/branches", h.GetSessionBranches)
+		sessions.PUT("/branch", h.SelectSessionBranch)
+	}
+}
+
+// GetSessions godoc
+// @Summary Get sessions
+// @Description Foydalanuvchining faol sessiyalari ro'yxati
+// @Tags auth
+// @Produce  json
+// @Response 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Router /auth/sessions [get]
+// @Security BearerAuth
+func (h *Handler) GetSessions(c *gin.Context) {
+	sessions, err := h.jwt.GetUserSessions(c.Request.Context(), c.GetString("userID"), c.GetString("sessionID"))
+	if err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	resp := make([]dto.Session, 0, len(sessions))
+	for _, s := range sessions {
+		resp = append(resp, dto.Session{
+			ID:             s.ID,
+			Device:         s.UserAgent,
+			ClientIP:       s.ClientIP,
+			CreatedAt:      time.Unix(s.CreatedAt, 0),
+			ExpiresAt:      time.Unix(s.ExpiresAt, 0),
+			IsCurrent:      s.IsCurrent,
+			ImpersonatedBy: s.ImpersonatorID,
+		})
+	}
+
+	response.Success(c, codes.Ok, resp)
+}
+
+// RevokeSession godoc
+// @Summary Revoke session
+// @Description Bitta sessiyani bekor qilish
+// @Tags auth
+// @Produce  json
+// @Param id path string true "Session ID"
+// @Response 200 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /auth/sessions/{id} [delete]
+// @Security BearerAuth
+func (h *Handler) RevokeSession(c *gin.Context) {
+	err := h.jwt.RevokeSession(c.Request.Context(), c.GetString("userID"), c.Param("id"))
+	if errors.Is(err, jwt.ErrSessionNotFound) {
+		response.Error(c, h.log, codes.SessionNotFound, err)
+		return
+	}
+	if err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	response.Success(c, codes.Ok, nil)
+}
+
+// RevokeOtherSessions godoc
+// @Summary Revoke other sessions
+// @Description Joriy sessiyadan tashqari barcha sessiyalarni bekor qilish
+// @Tags auth
+// @Produce  json
+// @Response 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Router /auth/sessions [delete]
+// @Security BearerAuth
+func (h *Handler) RevokeOtherSessions(c *gin.Context) {
+	if err := h.jwt.LogoutOthers(c.Request.Context(), c.GetString("userID"), c.GetString("sessionID")); err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	response.Success(c, codes.Ok, nil)
+}
+
+// GetSessionBranches godoc
+// @Summary Get session branches
+// @Description Foydalanuvchi ishlay oladigan filiallar va joriy sessiyaning faol filiali
+// @Tags auth
+// @Produce  json
+// @Response 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Router /auth/sessions/branches [get]
+// @Security BearerAuth
+func (h *Handler) GetSessionBranches(c *gin.Context) {
+	tenantID, ok := h.callerTenant(c)
+	if !ok {
+		return
+	}
+
+	branches, err := h.svc.Policy.GetUserBranches(c.GetString("userID"), tenantID)
+	if err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	response.Success(c, codes.Ok, dto.SessionBranches{
+		Active:   c.GetString("branchID"),
+		Branches: branches,
+	})
+}
+
+// SelectSessionBranch godoc
+// @Summary Select session branch
+// @Description Joriy sessiyaning faol filialini tanlash; bo'sh branch_id tanlovni bekor qiladi
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param request body dto.SelectBranchRequest true "Select Branch Request"
+// @Response 200 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Router /auth/sessions/branch [put]
+// @Security BearerAuth
+func (h *Handler) SelectSessionBranch(c *gin.Context) {
+	var req dto.SelectBranchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	if err := h.valid.Struct(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	tenantID, ok := h.callerTenant(c)
+	if !ok {
+		return
+	}
+
+	userID := c.GetString("userID")
+	if req.BranchID != "" {
+		branches, err := h.svc.Policy.GetUserBranches(userID, tenantID)
+		if err != nil {
+			response.Error(c, h.log, codes.InternalError, err)
+			return
+		}
+
+		if !slices.ContainsFunc(branches, func(branch model.Branch) bool { return branch.ID == req.BranchID }) {
+			response.Error(c, h.log, codes.BranchAccessDenied, errors.New("user has no role in the branch"))
+			return
+		}
+	}
+
+	err := h.jwt.SetSessionBranch(c.Request.Context(), userID, c.GetString("sessionID"), req.BranchID)
+	if errors.Is(err, jwt.ErrSessionNotFound) {
+		response.Error(c, h.log, codes.SessionNotFound, err)
+		return
+	}
+	if err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	response.Success(c, codes.Ok, nil)
+}
@@ -0,0 +1,286 @@
+package v1
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+
+	"github.com/asliddinberdiev/eirsystem/internal/dto"
+	"github.com/asliddinberdiev/eirsystem/internal/model"
+	"github.com/asliddinberdiev/eirsystem/internal/service"
+	"github.com/asliddinberdiev/eirsystem/pkg/codes"
+	"github.com/asliddinberdiev/eirsystem/pkg/oidc"
+	"github.com/asliddinberdiev/eirsystem/pkg/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var defaultSSOScopes = []string{"openid", "profile", "email"}
+
+func (h *Handler) initSSORoutes(auth *gin.RouterGroup) {
+	sso := auth.Group("/sso")
+	{
+		sso.POST("/authorize", h.SSOAuthorize)
+		sso.POST("/callback", h.SSOCallback)
+	}
+}
+
+// SSOAuthorize godoc
+// @Summary SSO authorize
+// @Description Klinikaning identity provider (OIDC) sahifasiga yo'naltirish uchun URL olish. Klinika subdomen yoki tenant_slug orqali aniqlanadi
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param request body dto.SSOAuthorizeRequest true "SSO Authorize Request"
+// @Response 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /auth/sso/authorize [post]
+func (h *Handler) SSOAuthorize(c *gin.Context) {
+	var req dto.SSOAuthorizeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	if err := h.valid.Struct(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	tenant, ok := h.resolveTenant(c, req.TenantSlug)
+	if !ok {
+		return
+	}
+
+	authURL, err := h.svc.OIDC.Begin(c.Request.Context(), tenant.ID)
+	if err != nil {
+		response.Error(c, h.log, ssoErrorCode(err), err)
+		return
+	}
+
+	response.Success(c, codes.Ok, dto.SSOAuthorizeResponse{
+		AuthorizationURL: authURL,
+	})
+}
+
+// SSOCallback godoc
+// @Summary SSO callback
+// @Description Identity provider qaytargan code va state orqali tizimga kirish va token olish. 2FA yoqilgan yoki rol uchun majburiy bo'lsa, parol bilan kirishdagi kabi avval challenge_token qaytariladi
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param request body dto.SSOCallbackRequest true "SSO Callback Request"
+// @Response 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Router /auth/sso/callback [post]
+func (h *Handler) SSOCallback(c *gin.Context) {
+	var req dto.SSOCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	if err := h.valid.Struct(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	login, err := h.svc.OIDC.Complete(c.Request.Context(), req.State, req.Code)
+	if err != nil {
+		response.Error(c, h.log, ssoErrorCode(err), err)
+		return
+	}
+
+	user := login.User
+	if !h.ensureActive(c, user) {
+		return
+	}
+
+	if !h.syncSSORole(c, login) {
+		return
+	}
+
+	// The identity provider's own factors are not trusted to satisfy the
+	// tenant's 2FA policy.
+	if h.startTwoFactor(c, user) {
+		return
+	}
+
+	resp, err := h.issueTokens(c, user)
+	if err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	response.Success(c, codes.Ok, resp)
+}
+
+// syncSSORole grants a provisioned user its role, or replaces the previous
+// built-in role of a user whose groups now map to another one.
+func (h *Handler) syncSSORole(c *gin.Context, login service.OIDCLogin) bool {
+	user := login.User
+	if login.Provisioned {
+		if err := h.svc.Policy.AddRoleToUser(user.ID, user.Role, user.TenantID); err != nil {
+			response.Error(c, h.log, codes.InternalError, err)
+			return false
+		}
+	}
+
+	if login.RoleChanged {
+		if err := h.svc.OIDC.SaveRole(c.Request.Context(), login); err != nil {
+			response.Error(c, h.log, codes.InternalError, err)
+			return false
+		}
+		if err := h.svc.Policy.ReplaceUserRole(user.ID, login.PreviousRole, user.Role, user.TenantID); err != nil {
+			response.Error(c, h.log, codes.InternalError, err)
+			return false
+		}
+	}
+
+	return true
+}
+
+// GetSSOProvider godoc
+// @Summary Get SSO provider
+// @Description Fetch the OpenID Connect provider of the caller's tenant; the client secret is never returned
+// @Tags tenant
+// @Produce  json
+// @Response 200 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /tenant/sso [get]
+// @Security BearerAuth
+func (h *Handler) GetSSOProvider(c *gin.Context) {
+	tenantID, ok := h.callerTenant(c)
+	if !ok {
+		return
+	}
+
+	provider, err := h.svc.OIDC.GetProvider(tenantID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		response.Error(c, h.log, codes.SSONotConfigured, err)
+		return
+	}
+	if err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	response.Success(c, codes.Ok, provider)
+}
+
+// SetSSOProvider godoc
+// @Summary Set SSO provider
+// @Description Configure the OpenID Connect provider of the caller's tenant. The issuer must serve a discovery document. An empty client_secret keeps the saved one. role_mapping maps IdP groups to roles; the most privileged match wins
+// @Tags tenant
+// @Accept  json
+// @Produce  json
+// @Param request body dto.SSOProviderRequest true "SSO Provider Request"
+// @Response 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /tenant/sso [put]
+// @Security BearerAuth
+func (h *Handler) SetSSOProvider(c *gin.Context) {
+	var req dto.SSOProviderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	if err := h.valid.Struct(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	tenantID, ok := h.callerTenant(c)
+	if !ok {
+		return
+	}
+
+	if req.ClientSecret == "" {
+		current, err := h.svc.OIDC.GetProvider(tenantID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Error(c, h.log, codes.InternalError, err)
+			return
+		}
+		req.ClientSecret = current.ClientSecret
+	}
+
+	scopes := req.Scopes
+	if len(scopes) == 0 {
+		scopes = defaultSSOScopes
+	}
+	if !slices.Contains(scopes, "openid") {
+		scopes = append([]string{"openid"}, scopes...)
+	}
+
+	provider := &model.TenantOIDCProvider{
+		TenantID:      tenantID,
+		Issuer:        req.Issuer,
+		ClientID:      req.ClientID,
+		ClientSecret:  req.ClientSecret,
+		RedirectURL:   req.RedirectURL,
+		Scopes:        scopes,
+		UsernameClaim: cmp.Or(req.UsernameClaim, "preferred_username"),
+		NameClaim:     cmp.Or(req.NameClaim, "name"),
+		GroupsClaim:   cmp.Or(req.GroupsClaim, "groups"),
+		RoleMapping:   req.RoleMapping,
+		DefaultRole:   req.DefaultRole,
+		AutoProvision: req.AutoProvision,
+		IsEnabled:     req.IsEnabled,
+	}
+	if provider.RoleMapping == nil {
+		provider.RoleMapping = map[string]string{}
+	}
+
+	if err := h.svc.OIDC.SetProvider(c.Request.Context(), provider); err != nil {
+		response.Error(c, h.log, ssoErrorCode(err), err)
+		return
+	}
+
+	response.Success(c, codes.Ok, provider)
+}
+
+// DeleteSSOProvider godoc
+// @Summary Delete SSO provider
+// @Description Turn off single sign-on for the caller's tenant. Linked users keep their accounts
+// @Tags tenant
+// @Produce  json
+// @Response 200 {object} response.Response
+// @Router /tenant/sso [delete]
+// @Security BearerAuth
+func (h *Handler) DeleteSSOProvider(c *gin.Context) {
+	tenantID, ok := h.callerTenant(c)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.OIDC.DeleteProvider(tenantID); err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	response.Success(c, codes.Ok, nil)
+}
+
+func ssoErrorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, service.ErrOIDCNotConfigured):
+		return codes.SSONotConfigured
+	case errors.Is(err, service.ErrOIDCStateInvalid):
+		return codes.SSOStateInvalid
+	case errors.Is(err, service.ErrOIDCUserNotFound):
+		return codes.SSOUserNotProvisioned
+	case errors.Is(err, service.ErrOIDCRoleUnmapped):
+		return codes.SSORoleUnmapped
+	case errors.Is(err, service.ErrOIDCUsernameExists):
+		return codes.UserAlreadyExists
+	case errors.Is(err, oidc.ErrDiscovery):
+		return codes.SSOProviderInvalid
+	case errors.Is(err, oidc.ErrExchange), errors.Is(err, oidc.ErrIDTokenInvalid):
+		return codes.SSOLoginFailed
+	default:
+		return codes.InternalError
+	}
+}
@@ -0,0 +1,291 @@
+package v1
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"net/netip"
+	"time"
+
+	"github.com/asliddinberdiev/eirsystem/internal/delivery/http/middleware"
+	"github.com/asliddinberdiev/eirsystem/internal/dto"
+	"github.com/asliddinberdiev/eirsystem/internal/model"
+	"github.com/asliddinberdiev/eirsystem/internal/service"
+	"github.com/asliddinberdiev/eirsystem/pkg/codes"
+	"github.com/asliddinberdiev/eirsystem/pkg/response"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// defaultBranchName names the branch every tenant starts with.
+const defaultBranchName = "Main"
+
+// initSystemRoutes registers the routes that manage the platform. Only the
+// system role may use them, so they declare no tenant permission.
+func (h *Handler) initSystemRoutes(api *gin.RouterGroup) {
+	system := api.Group("/system")
+	system.Use(middleware.NewJWTMiddleware(h.log, h.jwt, h.svc))
+	system.Use(middleware.SystemOnly(h.log))
+	{
+		system.POST("/tenants", h.OnboardTenant)
+		system.POST("/tenants/:id/renew", h.RenewSubscription)
+		system.POST("/tenants/:id/suspend", h.SuspendTenant)
+		system.POST("/tenants/:id/reactivate", h.ReactivateTenant)
+		system.GET("/tenants/:id/subscription-history", h.GetSubscriptionHistory)
+		system.DELETE("/lockouts/ip/:ip", h.ClearIPLockout)
+	}
+}
+
+// OnboardTenant godoc
+// @Summary Onboard tenant
+// @Description Create a clinic with its default branch, its owner account and staff profile, and the default role policies. The response holds the owner's temporary password, which has to be changed on the first sign-in
+// @Tags system
+// @Accept  json
+// @Produce  json
+// @Param request body dto.OnboardTenantRequest true "Onboard Tenant Request"
+// @Response 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Router /system/tenants [post]
+// @Security BearerAuth
+func (h *Handler) OnboardTenant(c *gin.Context) {
+	var req dto.OnboardTenantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	if err := h.valid.Struct(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	if !req.SubscriptionEndDate.After(time.Now()) {
+		response.Error(c, h.log, codes.InvalidRequest, errors.New("subscription_end_date must be in the future"))
+		return
+	}
+
+	tenant := &model.Tenant{
+		Name:                req.Name,
+		Slug:                req.Slug,
+		SubscriptionEndDate: req.SubscriptionEndDate,
+	}
+	branch := &model.Branch{
+		Name: cmp.Or(req.BranchName, defaultBranchName),
+		Slug: "main",
+	}
+	owner := &model.User{
+		FullName: req.Owner.FullName,
+		Username: req.Owner.Username,
+		Phone:    req.Owner.Phone,
+	}
+
+	password, err := h.svc.Tenant.Onboard(tenant, branch, owner)
+	if errors.Is(err, service.ErrTenantExists) {
+		response.Error(c, h.log, codes.TenantExists, err)
+		return
+	}
+	if err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	response.Success(c, codes.Ok, dto.OnboardTenantResponse{
+		Tenant: *tenant,
+		Branch: *branch,
+		Owner: dto.User{
+			ID:       owner.ID,
+			Username: owner.Username,
+			FullName: owner.FullName,
+			Role:     owner.Role,
+		},
+		Credentials: dto.OwnerCredentials{
+			TenantSlug:        tenant.Slug,
+			Username:          owner.Username,
+			TemporaryPassword: password.Password,
+			ExpiresAt:         password.ExpiresAt,
+		},
+	})
+}
+
+// RenewSubscription godoc
+// @Summary Renew subscription
+// @Description Move the end of a tenant's subscription to end_date, which lifts the grace period or the block once it has expired. A suspended tenant stays suspended until it is reactivated
+// @Tags system
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param request body dto.RenewSubscriptionRequest true "Renew Subscription Request"
+// @Response 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /system/tenants/{id}/renew [post]
+// @Security BearerAuth
+func (h *Handler) RenewSubscription(c *gin.Context) {
+	tenantID, ok := h.tenantParam(c)
+	if !ok {
+		return
+	}
+
+	var req dto.RenewSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	if err := h.valid.Struct(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	if !req.EndDate.After(time.Now()) {
+		response.Error(c, h.log, codes.InvalidRequest, errors.New("end_date must be in the future"))
+		return
+	}
+
+	tenant, err := h.svc.Tenant.Renew(c.Request.Context(), tenantID, req.EndDate, req.Reason, actorID(c))
+	if err != nil {
+		response.Error(c, h.log, subscriptionErrorCode(err), err)
+		return
+	}
+
+	response.Success(c, codes.Ok, tenant)
+}
+
+// SuspendTenant godoc
+// @Summary Suspend tenant
+// @Description Block every user of a tenant, whatever its subscription end date, until it is reactivated
+// @Tags system
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param request body dto.SubscriptionChangeRequest true "Suspend Request"
+// @Response 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /system/tenants/{id}/suspend [post]
+// @Security BearerAuth
+func (h *Handler) SuspendTenant(c *gin.Context) {
+	h.changeSubscription(c, h.svc.Tenant.Suspend)
+}
+
+// ReactivateTenant godoc
+// @Summary Reactivate tenant
+// @Description Lift the suspension of a tenant. Its users get the access its subscription end date allows
+// @Tags system
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Param request body dto.SubscriptionChangeRequest true "Reactivate Request"
+// @Response 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /system/tenants/{id}/reactivate [post]
+// @Security BearerAuth
+func (h *Handler) ReactivateTenant(c *gin.Context) {
+	h.changeSubscription(c, h.svc.Tenant.Reactivate)
+}
+
+// GetSubscriptionHistory godoc
+// @Summary Get subscription history
+// @Description Fetch the renewals, suspensions and reactivations of a tenant, newest first
+// @Tags system
+// @Produce  json
+// @Param id path string true "Tenant ID"
+// @Response 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /system/tenants/{id}/subscription-history [get]
+// @Security BearerAuth
+func (h *Handler) GetSubscriptionHistory(c *gin.Context) {
+	tenantID, ok := h.tenantParam(c)
+	if !ok {
+		return
+	}
+
+	events, err := h.svc.Tenant.GetSubscriptionHistory(tenantID)
+	if err != nil {
+		response.Error(c, h.log, subscriptionErrorCode(err), err)
+		return
+	}
+
+	response.Success(c, codes.Ok, events)
+}
+
+// ClearIPLockout godoc
+// @Summary Clear IP sign-in lockout
+// @Description Lift the lockout caused by failed sign-in attempts from a client IP and reset its failure counter. The IP is shared by every tenant
+// @Tags system
+// @Produce  json
+// @Param ip path string true "Client IP"
+// @Response 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Router /system/lockouts/ip/{ip} [delete]
+// @Security BearerAuth
+func (h *Handler) ClearIPLockout(c *gin.Context) {
+	ip, err := netip.ParseAddr(c.Param("ip"))
+	if err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	if err := h.svc.LoginAttempt.ResetIP(c.Request.Context(), ip.Unmap().String()); err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	response.Success(c, codes.Ok, nil)
+}
+
+type subscriptionChange func(ctx context.Context, tenantID, reason string, actorID *string) (model.Tenant, error)
+
+func (h *Handler) changeSubscription(c *gin.Context, change subscriptionChange) {
+	tenantID, ok := h.tenantParam(c)
+	if !ok {
+		return
+	}
+
+	var req dto.SubscriptionChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	if err := h.valid.Struct(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	tenant, err := change(c.Request.Context(), tenantID, req.Reason, actorID(c))
+	if err != nil {
+		response.Error(c, h.log, subscriptionErrorCode(err), err)
+		return
+	}
+
+	response.Success(c, codes.Ok, tenant)
+}
+
+func (h *Handler) tenantParam(c *gin.Context) (string, bool) {
+	tenantID := c.Param("id")
+	if err := uuid.Validate(tenantID); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return "", false
+	}
+	return tenantID, true
+}
+
+func subscriptionErrorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, service.ErrTenantNotFound):
+		return codes.TenantNotFound
+	case errors.Is(err, service.ErrTenantSuspended), errors.Is(err, service.ErrTenantNotSuspended):
+		return codes.InvalidRequest
+	default:
+		return codes.InternalError
+	}
+}
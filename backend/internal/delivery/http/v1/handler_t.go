@@ -2,16 +2,17 @@ package v1
 
 import (
 	"github.com/asliddinberdiev/eirsystem/pkg/codes"
+	"github.com/asliddinberdiev/eirsystem/pkg/permission"
 	"github.com/asliddinberdiev/eirsystem/pkg/response"
 	"github.com/gin-gonic/gin"
 )
 
 func (h *Handler) initTestRoutes(api *gin.RouterGroup) {
-	test := api.Group("/test")
+	test := h.permit(api.Group("/test"))
 	{
-		test.GET("/owner", h.TestOwner)
-		test.GET("/doctor", h.TestDoctor)
-		test.GET("/nurse", h.TestNurse)
+		test.GET("/owner", permission.TestOwner, h.TestOwner)
+		test.GET("/doctor", permission.TestDoctor, h.TestDoctor)
+		test.GET("/nurse", permission.TestNurse, h.TestNurse)
 	}
 }
 
@@ -0,0 +1,458 @@
+package v1
+
+import (
+	"errors"
+
+	"github.com/asliddinberdiev/eirsystem/internal/delivery/http/middleware"
+	"github.com/asliddinberdiev/eirsystem/internal/dto"
+	"github.com/asliddinberdiev/eirsystem/internal/model"
+	"github.com/asliddinberdiev/eirsystem/internal/service"
+	"github.com/asliddinberdiev/eirsystem/pkg/codes"
+	"github.com/asliddinberdiev/eirsystem/pkg/jwt"
+	"github.com/asliddinberdiev/eirsystem/pkg/permission"
+	"github.com/asliddinberdiev/eirsystem/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) initTwoFactorRoutes(auth *gin.RouterGroup) {
+	twoFactor := auth.Group("/2fa")
+	{
+		twoFactor.POST("/verify", h.VerifyTwoFactor)
+		twoFactor.POST("/enroll", h.EnrollTwoFactor)
+		twoFactor.POST("/enroll/confirm", h.ConfirmTwoFactorEnroll)
+	}
+
+	self := twoFactor.Group("")
+	self.Use(middleware.NewJWTMiddleware(h.log, h.jwt, h.svc))
+	self.Use(middleware.UserOnly(h.log))
+	{
+		self.POST("/setup", h.SetupTwoFactor)
+		self.POST("/enable", h.EnableTwoFactor)
+		self.POST("/disable", h.DisableTwoFactor)
+		self.POST("/recovery-codes", h.RegenerateRecoveryCodes)
+	}
+}
+
+func (h *Handler) initTenantRoutes(api *gin.RouterGroup) {
+	tenant := h.permit(api.Group("/tenant"))
+	{
+		tenant.GET("/two-factor", permission.TenantSettingsRead, h.GetTwoFactorRoles)
+		tenant.PUT("/two-factor", permission.TenantSettingsUpdate, h.SetTwoFactorRoles)
+		tenant.GET("/password-policy", permission.TenantSettingsRead, h.GetPasswordPolicy)
+		tenant.PUT("/password-policy", permission.TenantSettingsUpdate, h.SetPasswordPolicy)
+		tenant.GET("/sso", permission.TenantSettingsRead, h.GetSSOProvider)
+		tenant.PUT("/sso", permission.TenantSettingsUpdate, h.SetSSOProvider)
+		tenant.DELETE("/sso", permission.TenantSettingsUpdate, h.DeleteSSOProvider)
+	}
+}
+
+func (h *Handler) sendChallenge(c *gin.Context, userID, purpose string, code codes.Code) {
+	token, err := h.jwt.GenerateChallenge(c.Request.Context(), userID, purpose)
+	if err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	response.Success(c, code, dto.TwoFactorChallengeResponse{
+		ChallengeToken: token,
+		ExpiresIn:      int(jwt.ChallengeTTL.Seconds()),
+	})
+}
+
+// VerifyTwoFactor godoc
+// @Summary Verify two-factor code
+// @Description Parol bosqichidan keyin TOTP yoki zaxira kod bilan tizimga kirishni yakunlash
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param request body dto.TwoFactorVerifyRequest true "Verify Request"
+// @Response 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Router /auth/2fa/verify [post]
+func (h *Handler) VerifyTwoFactor(c *gin.Context) {
+	var req dto.TwoFactorVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	if err := h.valid.Struct(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	claims, err := h.jwt.ValidateChallenge(c.Request.Context(), req.ChallengeToken, jwt.ChallengeTwoFactor)
+	if err != nil {
+		response.Error(c, h.log, codes.AuthChallengeInvalid, err)
+		return
+	}
+
+	user, err := h.svc.User.GetByID(claims.UserID)
+	if err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	lockout, err := h.svc.LoginAttempt.Check(c.Request.Context(), user.TenantID, user.Username, c.ClientIP())
+	if err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+	if lockout > 0 {
+		h.lockedOut(c, lockout)
+		return
+	}
+
+	if req.Code != "" {
+		err = h.svc.TwoFactor.Verify(c.Request.Context(), claims.UserID, req.Code)
+	} else {
+		err = h.svc.TwoFactor.VerifyRecoveryCode(c.Request.Context(), claims.UserID, req.RecoveryCode)
+	}
+	if errors.Is(err, service.ErrTwoFactorInvalidCode) {
+		h.twoFactorFailed(c, user, err)
+		return
+	}
+	if err != nil {
+		response.Error(c, h.log, twoFactorErrorCode(err), err)
+		return
+	}
+
+	resp, ok := h.completeChallenge(c, claims)
+	if !ok {
+		return
+	}
+
+	response.Success(c, codes.Ok, resp)
+}
+
+// EnrollTwoFactor godoc
+// @Summary Start required two-factor enrollment
+// @Description Tenant talab qilganda tizimga kirish paytida 2FA sozlashni boshlash
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param request body dto.TwoFactorEnrollRequest true "Enroll Request"
+// @Response 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Router /auth/2fa/enroll [post]
+func (h *Handler) EnrollTwoFactor(c *gin.Context) {
+	var req dto.TwoFactorEnrollRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	if err := h.valid.Struct(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	claims, err := h.jwt.ValidateChallenge(c.Request.Context(), req.ChallengeToken, jwt.ChallengeTwoFactorEnroll)
+	if err != nil {
+		response.Error(c, h.log, codes.AuthChallengeInvalid, err)
+		return
+	}
+
+	h.setupTwoFactor(c, claims.UserID)
+}
+
+// ConfirmTwoFactorEnroll godoc
+// @Summary Confirm required two-factor enrollment
+// @Description 2FA sozlashni kod bilan tasdiqlash, zaxira kodlar va token olish
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param request body dto.TwoFactorEnrollConfirmRequest true "Enroll Confirm Request"
+// @Response 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Router /auth/2fa/enroll/confirm [post]
+func (h *Handler) ConfirmTwoFactorEnroll(c *gin.Context) {
+	var req dto.TwoFactorEnrollConfirmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	if err := h.valid.Struct(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	claims, err := h.jwt.ValidateChallenge(c.Request.Context(), req.ChallengeToken, jwt.ChallengeTwoFactorEnroll)
+	if err != nil {
+		response.Error(c, h.log, codes.AuthChallengeInvalid, err)
+		return
+	}
+
+	recoveryCodes, err := h.svc.TwoFactor.Enable(c.Request.Context(), claims.UserID, req.Code)
+	if err != nil {
+		response.Error(c, h.log, twoFactorErrorCode(err), err)
+		return
+	}
+
+	resp, ok := h.completeChallenge(c, claims)
+	if !ok {
+		return
+	}
+
+	response.Success(c, codes.Ok, dto.TwoFactorEnrollConfirmResponse{
+		SignInResponse: resp,
+		RecoveryCodes:  recoveryCodes,
+	})
+}
+
+// SetupTwoFactor godoc
+// @Summary Start two-factor setup
+// @Description Autentifikator ilovasi uchun yangi TOTP kalit va QR URI olish
+// @Tags auth
+// @Produce  json
+// @Response 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /auth/2fa/setup [post]
+// @Security BearerAuth
+func (h *Handler) SetupTwoFactor(c *gin.Context) {
+	h.setupTwoFactor(c, c.GetString("userID"))
+}
+
+// EnableTwoFactor godoc
+// @Summary Enable two-factor authentication
+// @Description TOTP kodni tasdiqlab 2FA ni yoqish va zaxira kodlarni olish
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param request body dto.TwoFactorCodeRequest true "Code Request"
+// @Response 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /auth/2fa/enable [post]
+// @Security BearerAuth
+func (h *Handler) EnableTwoFactor(c *gin.Context) {
+	var req dto.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	if err := h.valid.Struct(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	recoveryCodes, err := h.svc.TwoFactor.Enable(c.Request.Context(), c.GetString("userID"), req.Code)
+	if err != nil {
+		response.Error(c, h.log, twoFactorErrorCode(err), err)
+		return
+	}
+
+	response.Success(c, codes.Ok, dto.TwoFactorRecoveryCodesResponse{RecoveryCodes: recoveryCodes})
+}
+
+// DisableTwoFactor godoc
+// @Summary Disable two-factor authentication
+// @Description Joriy TOTP kod bilan 2FA ni o'chirish
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param request body dto.TwoFactorCodeRequest true "Code Request"
+// @Response 200 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Router /auth/2fa/disable [post]
+// @Security BearerAuth
+func (h *Handler) DisableTwoFactor(c *gin.Context) {
+	var req dto.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	if err := h.valid.Struct(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	required, err := h.svc.TwoFactor.IsRequired(c.GetString("tenantID"), c.GetString("userRole"))
+	if err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+	if required {
+		response.Error(c, h.log, codes.UserActionForbidden, errors.New("two-factor authentication is required for this role"))
+		return
+	}
+
+	if err := h.svc.TwoFactor.Disable(c.Request.Context(), c.GetString("userID"), req.Code); err != nil {
+		response.Error(c, h.log, twoFactorErrorCode(err), err)
+		return
+	}
+
+	response.Success(c, codes.Ok, nil)
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Regenerate recovery codes
+// @Description Eski zaxira kodlarni bekor qilib yangilarini olish
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param request body dto.TwoFactorCodeRequest true "Code Request"
+// @Response 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Router /auth/2fa/recovery-codes [post]
+// @Security BearerAuth
+func (h *Handler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req dto.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	if err := h.valid.Struct(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	recoveryCodes, err := h.svc.TwoFactor.RegenerateRecoveryCodes(c.Request.Context(), c.GetString("userID"), req.Code)
+	if err != nil {
+		response.Error(c, h.log, twoFactorErrorCode(err), err)
+		return
+	}
+
+	response.Success(c, codes.Ok, dto.TwoFactorRecoveryCodesResponse{RecoveryCodes: recoveryCodes})
+}
+
+// GetTwoFactorRoles godoc
+// @Summary Get roles that require two-factor authentication
+// @Description Fetch the roles that must use 2FA in the caller's tenant
+// @Tags tenant
+// @Produce  json
+// @Response 200 {object} response.Response
+// @Router /tenant/two-factor [get]
+// @Security BearerAuth
+func (h *Handler) GetTwoFactorRoles(c *gin.Context) {
+	tenantID, ok := h.callerTenant(c)
+	if !ok {
+		return
+	}
+
+	roles, err := h.svc.TwoFactor.GetRequiredRoles(tenantID)
+	if err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	response.Success(c, codes.Ok, dto.TwoFactorRolesResponse{Roles: roles})
+}
+
+// SetTwoFactorRoles godoc
+// @Summary Set roles that require two-factor authentication
+// @Description Replace the roles that must use 2FA in the caller's tenant
+// @Tags tenant
+// @Accept  json
+// @Produce  json
+// @Param request body dto.TwoFactorRolesRequest true "Roles Request"
+// @Response 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /tenant/two-factor [put]
+// @Security BearerAuth
+func (h *Handler) SetTwoFactorRoles(c *gin.Context) {
+	var req dto.TwoFactorRolesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	if err := h.valid.Struct(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	tenantID, ok := h.callerTenant(c)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.TwoFactor.SetRequiredRoles(tenantID, req.Roles); err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	response.Success(c, codes.Ok, nil)
+}
+
+func (h *Handler) setupTwoFactor(c *gin.Context, userID string) {
+	setup, err := h.svc.TwoFactor.Setup(c.Request.Context(), userID)
+	if err != nil {
+		response.Error(c, h.log, twoFactorErrorCode(err), err)
+		return
+	}
+
+	response.Success(c, codes.Ok, dto.TwoFactorSetupResponse{
+		Secret:          setup.Secret,
+		ProvisioningURI: setup.ProvisioningURI,
+	})
+}
+
+// twoFactorFailed counts a wrong code like a wrong password, against the
+// account and the client IP.
+func (h *Handler) twoFactorFailed(c *gin.Context, user model.User, err error) {
+	lockout, failErr := h.svc.LoginAttempt.Fail(c.Request.Context(), user.TenantID, user.Username, c.ClientIP())
+	if failErr != nil {
+		response.Error(c, h.log, codes.InternalError, failErr)
+		return
+	}
+	if lockout > 0 {
+		h.lockedOut(c, lockout)
+		return
+	}
+
+	response.Error(c, h.log, codes.TwoFactorInvalidCode, err)
+}
+
+// completeChallenge burns the challenge and issues the session it was guarding.
+// The sign-in failures of the account are forgotten only now.
+func (h *Handler) completeChallenge(c *gin.Context, claims *jwt.ChallengeClaims) (dto.SignInResponse, bool) {
+	if err := h.jwt.ConsumeChallenge(c.Request.Context(), claims); err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return dto.SignInResponse{}, false
+	}
+
+	user, err := h.svc.User.GetByID(claims.UserID)
+	if err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return dto.SignInResponse{}, false
+	}
+
+	// The user may have been blocked while the challenge was open.
+	if !h.ensureActive(c, user) {
+		return dto.SignInResponse{}, false
+	}
+
+	if err := h.svc.LoginAttempt.Reset(c.Request.Context(), user.TenantID, user.Username); err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return dto.SignInResponse{}, false
+	}
+
+	resp, err := h.issueTokens(c, user)
+	if err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return dto.SignInResponse{}, false
+	}
+
+	return resp, true
+}
+
+func twoFactorErrorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, service.ErrTwoFactorInvalidCode):
+		return codes.TwoFactorInvalidCode
+	case errors.Is(err, service.ErrTwoFactorNotEnabled):
+		return codes.TwoFactorNotEnabled
+	case errors.Is(err, service.ErrTwoFactorAlreadyEnabled):
+		return codes.TwoFactorAlreadyEnabled
+	case errors.Is(err, service.ErrTwoFactorSetupExpired):
+		return codes.TwoFactorSetupExpired
+	default:
+		return codes.InternalError
+	}
+}
@@ -2,21 +2,34 @@
 package v1
 
 import (
+	"errors"
+	"time"
+
+	"github.com/asliddinberdiev/eirsystem/internal/dto"
+	"github.com/asliddinberdiev/eirsystem/internal/model"
 	"github.com/asliddinberdiev/eirsystem/pkg/codes"
+	"github.com/asliddinberdiev/eirsystem/pkg/permission"
 	"github.com/asliddinberdiev/eirsystem/pkg/response"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 func (h *Handler) initUserRoutes(api *gin.RouterGroup) {
-	users := api.Group("/users")
+	users := h.permit(api.Group("/users"))
 	{
-		users.GET("", h.GetAll)
+		users.GET("", permission.UsersRead, h.GetAll)
+		users.GET("/:id/blocks", permission.UsersRead, h.GetUserBlocks)
+		users.POST("/:id/block", permission.UsersBlock, h.BlockUser)
+		users.POST("/:id/unblock", permission.UsersBlock, h.UnblockUser)
+		users.DELETE("/:id/lockout", permission.UsersUnlock, h.ClearUserLockout)
+		users.POST("/:id/password/reset", permission.UsersResetPassword, h.ResetUserPassword)
+		users.POST("/:id/impersonate", permission.UsersImpersonate, h.ImpersonateUser)
 	}
 }
 
 // GetAll godoc
 // @Summary Get users
-// @Description Fetch list of users
+// @Description Fetch the users of the caller's tenant; system callers get the users of every tenant
 // @Tags users
 // @Accept  json
 // @Produce  json
@@ -25,10 +38,207 @@ func (h *Handler) initUserRoutes(api *gin.RouterGroup) {
 // @Router /users [get]
 // @Security BearerAuth
 func (h *Handler) GetAll(c *gin.Context) {
-	users, err := h.svc.User.GetAll()
+	var (
+		users []model.User
+		err   error
+	)
+	if isSystem(c) {
+		users, err = h.svc.User.GetAllTenants()
+	} else {
+		tenantID, ok := h.callerTenant(c)
+		if !ok {
+			return
+		}
+		users, err = h.svc.User.GetAll(tenantID)
+	}
 	if err != nil {
 		response.Error(c, h.log, codes.InternalError, err)
 		return
 	}
 	response.Success(c, codes.Ok, users)
 }
+
+// BlockUser godoc
+// @Summary Block user
+// @Description Block a staff account, revoke all of its sessions and record the reason
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param id path string true "User ID"
+// @Param request body dto.BlockUserRequest true "Block Request"
+// @Response 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /users/{id}/block [post]
+// @Security BearerAuth
+func (h *Handler) BlockUser(c *gin.Context) {
+	var req dto.BlockUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	if err := h.valid.Struct(&req); err != nil {
+		response.Error(c, h.log, codes.InvalidRequest, err)
+		return
+	}
+
+	var ttl time.Duration
+	if req.ExpiresAt != nil {
+		ttl = time.Until(*req.ExpiresAt)
+		if ttl <= 0 {
+			response.Error(c, h.log, codes.InvalidRequest, errors.New("expires_at must be in the future"))
+			return
+		}
+	}
+
+	user, ok := h.getManagedUser(c)
+	if !ok {
+		return
+	}
+
+	block := &model.UserBlock{
+		ID:        uuid.New().String(),
+		TenantID:  nullableString(user.TenantID),
+		UserID:    user.ID,
+		BlockedBy: actorID(c),
+		Reason:    req.Reason,
+		ExpiresAt: req.ExpiresAt,
+	}
+
+	if err := h.svc.UserBlock.Block(c.Request.Context(), block); err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	if err := h.jwt.Block(c.Request.Context(), user.ID, ttl); err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	if err := h.jwt.LogoutAll(c.Request.Context(), user.ID); err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	response.Success(c, codes.Ok, block)
+}
+
+// UnblockUser godoc
+// @Summary Unblock user
+// @Description Lift every active block of a staff account and reactivate it
+// @Tags users
+// @Produce  json
+// @Param id path string true "User ID"
+// @Response 200 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /users/{id}/unblock [post]
+// @Security BearerAuth
+func (h *Handler) UnblockUser(c *gin.Context) {
+	user, ok := h.getManagedUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.UserBlock.Unblock(c.Request.Context(), user.ID, actorID(c)); err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	if err := h.jwt.Unblock(c.Request.Context(), user.ID); err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	response.Success(c, codes.Ok, nil)
+}
+
+// ClearUserLockout godoc
+// @Summary Clear sign-in lockout
+// @Description Lift the lockout caused by failed sign-in attempts and reset the failure counter of a staff account. A lockout of the client IP stays until the system role clears it
+// @Tags users
+// @Produce  json
+// @Param id path string true "User ID"
+// @Response 200 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /users/{id}/lockout [delete]
+// @Security BearerAuth
+func (h *Handler) ClearUserLockout(c *gin.Context) {
+	user, ok := h.getManagedUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.LoginAttempt.Reset(c.Request.Context(), user.TenantID, user.Username); err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	response.Success(c, codes.Ok, nil)
+}
+
+// GetUserBlocks godoc
+// @Summary Get user block history
+// @Description Fetch the block history of a staff account, newest first
+// @Tags users
+// @Produce  json
+// @Param id path string true "User ID"
+// @Response 200 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /users/{id}/blocks [get]
+// @Security BearerAuth
+func (h *Handler) GetUserBlocks(c *gin.Context) {
+	user, ok := h.getManagedUser(c)
+	if !ok {
+		return
+	}
+
+	blocks, err := h.svc.UserBlock.GetAllByUserID(user.ID)
+	if err != nil {
+		response.Error(c, h.log, codes.InternalError, err)
+		return
+	}
+
+	response.Success(c, codes.Ok, blocks)
+}
+
+// getManagedUser loads the user from the :id path parameter and checks that the
+// caller may manage it: tenant users only see their own tenant, nobody manages
+// themselves or a system account, and only the system role manages owners.
+func (h *Handler) getManagedUser(c *gin.Context) (model.User, bool) {
+	user, err := h.svc.User.GetByID(c.Param("id"))
+	if err != nil || (!isSystem(c) && user.TenantID != c.GetString("tenantID")) {
+		response.Error(c, h.log, codes.UserNotFound, errors.New("user not found"))
+		return model.User{}, false
+	}
+
+	if user.ID == c.GetString("userID") || user.Role == "system" || (user.Role == "owner" && !isSystem(c)) {
+		response.Error(c, h.log, codes.UserActionForbidden, errors.New("this user cannot be managed by the caller"))
+		return model.User{}, false
+	}
+
+	return user, true
+}
+
+func isSystem(c *gin.Context) bool {
+	return c.GetString("userRole") == "system"
+}
+
+// actorID returns the user behind the request for audit columns, or nil when a
+// service account made it, since those columns reference users.
+func actorID(c *gin.Context) *string {
+	if c.GetString("apiKeyID") != "" {
+		return nil
+	}
+	return nullableString(c.GetString("userID"))
+}
+
+func nullableString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
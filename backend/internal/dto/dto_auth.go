@@ -2,14 +2,21 @@
 package dto
 
 type SignInRequest struct {
+	TenantSlug string `json:"tenant_slug" validate:"omitempty,max=50"`
+	Username   string `json:"username" validate:"required,min=3,max=255"`
+	Password   string `json:"password" validate:"required,min=6"`
+}
+
+type SystemSignInRequest struct {
 	Username string `json:"username" validate:"required,min=3,max=255"`
 	Password string `json:"password" validate:"required,min=6"`
 }
 
 type SignInResponse struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
-	User         User   `json:"user"`
+	AccessToken        string `json:"access_token"`
+	RefreshToken       string `json:"refresh_token"`
+	MustChangePassword bool   `json:"must_change_password"`
+	User               User   `json:"user"`
 }
 
 type RefreshTokenRequest struct {
@@ -21,3 +28,6 @@ type RefreshTokenResponse struct {
 	RefreshToken string `json:"refresh_token"`
 }
 
+type LockoutResponse struct {
+	RetryAfter int `json:"retry_after"`
+}
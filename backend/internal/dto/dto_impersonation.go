@@ -0,0 +1,18 @@
+package dto
+
+import "time"
+
+type ImpersonateRequest struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+type ImpersonationResponse struct {
+	AccessToken string    `json:"access_token"`
+	SessionID   string    `json:"session_id"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	User        User      `json:"user"`
+}
+
+type ImpersonationConsentRequest struct {
+	Allow bool `json:"allow"`
+}
@@ -0,0 +1,26 @@
+package dto
+
+import "time"
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,max=128"`
+}
+
+type PasswordPolicyViolationResponse struct {
+	Violations []string `json:"violations"`
+}
+
+type TemporaryPasswordResponse struct {
+	TemporaryPassword string    `json:"temporary_password"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+type PasswordPolicyRequest struct {
+	MinLength     int  `json:"min_length" validate:"min=8,max=128"`
+	RequireUpper  bool `json:"require_upper"`
+	RequireLower  bool `json:"require_lower"`
+	RequireDigit  bool `json:"require_digit"`
+	RequireSymbol bool `json:"require_symbol"`
+	HistorySize   int  `json:"history_size" validate:"min=0,max=24"`
+}
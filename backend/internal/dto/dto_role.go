@@ -0,0 +1,38 @@
+package dto
+
+type CreateRoleRequest struct {
+	Name        string `json:"name" validate:"required,min=2,max=50,lowercase,excludesall= /:"`
+	Description string `json:"description" validate:"max=500"`
+}
+
+type GrantPermissionRequest struct {
+	Permission string `json:"permission" validate:"required,max=100,contains=:"`
+}
+
+type Permission struct {
+	Name   string   `json:"name"`
+	Routes []string `json:"routes"`
+}
+
+type AssignRoleRequest struct {
+	Role     string `json:"role" validate:"required,min=2,max=50"`
+	BranchID string `json:"branch_id" validate:"omitempty,uuid"`
+}
+
+type ExplainRequest struct {
+	UserID   string `json:"user_id" validate:"required,uuid"`
+	BranchID string `json:"branch_id" validate:"omitempty,uuid"`
+	Method   string `json:"method" validate:"required,oneof=GET POST PUT DELETE"`
+	Path     string `json:"path" validate:"required,startswith=/,max=500"`
+}
+
+type PolicyChange struct {
+	Action     string `json:"action" validate:"required,oneof=grant revoke"`
+	Role       string `json:"role" validate:"required,min=2,max=50"`
+	Permission string `json:"permission" validate:"required,max=100,contains=:"`
+}
+
+type DryRunRequest struct {
+	TenantID string         `json:"tenant_id" validate:"omitempty,uuid"`
+	Changes  []PolicyChange `json:"changes" validate:"required,min=1,max=50,dive"`
+}
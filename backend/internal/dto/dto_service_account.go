@@ -0,0 +1,24 @@
+package dto
+
+import "time"
+
+type CreateServiceAccountRequest struct {
+	Name        string `json:"name" validate:"required,min=3,max=100"`
+	Description string `json:"description" validate:"max=500"`
+	Role        string `json:"role" validate:"required,oneof=admin doctor nurse technician reception"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" validate:"required,min=3,max=100"`
+	Scopes    []string   `json:"scopes" validate:"dive,min=3,max=200"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type APIKeyCreatedResponse struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Key       string     `json:"key"`
+	Prefix    string     `json:"prefix"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
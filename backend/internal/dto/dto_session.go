@@ -0,0 +1,26 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/asliddinberdiev/eirsystem/internal/model"
+)
+
+type Session struct {
+	ID             string    `json:"id"`
+	Device         string    `json:"device"`
+	ClientIP       string    `json:"client_ip"`
+	CreatedAt      time.Time `json:"created_at"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	IsCurrent      bool      `json:"is_current"`
+	ImpersonatedBy string    `json:"impersonated_by,omitempty"`
+}
+
+type SessionBranches struct {
+	Active   string         `json:"active,omitempty"`
+	Branches []model.Branch `json:"branches"`
+}
+
+type SelectBranchRequest struct {
+	BranchID string `json:"branch_id" validate:"omitempty,uuid"`
+}
@@ -0,0 +1,29 @@
+package dto
+
+type SSOAuthorizeRequest struct {
+	TenantSlug string `json:"tenant_slug" validate:"omitempty,max=50"`
+}
+
+type SSOAuthorizeResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+}
+
+type SSOCallbackRequest struct {
+	State string `json:"state" validate:"required,max=100"`
+	Code  string `json:"code" validate:"required,max=2048"`
+}
+
+type SSOProviderRequest struct {
+	Issuer        string            `json:"issuer" validate:"required,url,max=500"`
+	ClientID      string            `json:"client_id" validate:"required,max=255"`
+	ClientSecret  string            `json:"client_secret" validate:"max=1000"`
+	RedirectURL   string            `json:"redirect_url" validate:"required,url,max=500"`
+	Scopes        []string          `json:"scopes" validate:"dive,min=1,max=100"`
+	UsernameClaim string            `json:"username_claim" validate:"max=100"`
+	NameClaim     string            `json:"name_claim" validate:"max=100"`
+	GroupsClaim   string            `json:"groups_claim" validate:"max=100"`
+	RoleMapping   map[string]string `json:"role_mapping" validate:"dive,keys,min=1,max=255,endkeys,oneof=admin doctor nurse technician reception"`
+	DefaultRole   *string           `json:"default_role" validate:"omitempty,oneof=admin doctor nurse technician reception"`
+	AutoProvision bool              `json:"auto_provision"`
+	IsEnabled     bool              `json:"is_enabled"`
+}
@@ -0,0 +1,46 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/asliddinberdiev/eirsystem/internal/model"
+)
+
+type OnboardTenantRequest struct {
+	Name                string       `json:"name" validate:"required,min=2,max=100"`
+	Slug                string       `json:"slug" validate:"required,min=3,max=50,hostname_rfc1123,excludes=."`
+	SubscriptionEndDate time.Time    `json:"subscription_end_date" validate:"required"`
+	BranchName          string       `json:"branch_name" validate:"omitempty,min=2,max=100"`
+	Owner               OnboardOwner `json:"owner"`
+}
+
+type OnboardOwner struct {
+	FullName string `json:"full_name" validate:"required,min=2,max=100"`
+	Username string `json:"username" validate:"required,min=3,max=50"`
+	Phone    string `json:"phone" validate:"omitempty,max=20"`
+}
+
+type OnboardTenantResponse struct {
+	Tenant      model.Tenant     `json:"tenant"`
+	Branch      model.Branch     `json:"branch"`
+	Owner       User             `json:"owner"`
+	Credentials OwnerCredentials `json:"credentials"`
+}
+
+// OwnerCredentials is what the owner signs in with the first time; the
+// temporary password has to be changed then.
+type OwnerCredentials struct {
+	TenantSlug        string    `json:"tenant_slug"`
+	Username          string    `json:"username"`
+	TemporaryPassword string    `json:"temporary_password"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+type RenewSubscriptionRequest struct {
+	EndDate time.Time `json:"end_date" validate:"required"`
+	Reason  string    `json:"reason" validate:"omitempty,max=500"`
+}
+
+type SubscriptionChangeRequest struct {
+	Reason string `json:"reason" validate:"required,min=3,max=500"`
+}
@@ -1,9 +1,16 @@
 // Package dto provides data transfer objects for the application.
 package dto
 
+import "time"
+
 type User struct {
 	ID       string `json:"id"`
 	Username string `json:"username"`
 	FullName string `json:"full_name"`
 	Role     string `json:"role"`
-}
\ No newline at end of file
+}
+
+type BlockUserRequest struct {
+	Reason    string     `json:"reason" validate:"required,min=3,max=500"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
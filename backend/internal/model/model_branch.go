@@ -0,0 +1,26 @@
+package model
+
+import "slices"
+
+type Branch struct {
+	ID       string  `json:"id"`
+	TenantID string  `json:"tenant_id"`
+	Name     string  `json:"name"`
+	Slug     string  `json:"slug"`
+	Address  *string `json:"address"`
+	Phone    *string `json:"phone"`
+}
+
+// BranchScope is the set of branches the caller may act on for the current
+// request. All is set for tenant-wide roles; otherwise IDs lists the branches
+// where the caller holds a role granting the route's permission. Active is the
+// branch chosen for the session, if any.
+type BranchScope struct {
+	Active string   `json:"active,omitempty"`
+	All    bool     `json:"all"`
+	IDs    []string `json:"ids"`
+}
+
+func (s BranchScope) Allows(branchID string) bool {
+	return s.All || slices.Contains(s.IDs, branchID)
+}
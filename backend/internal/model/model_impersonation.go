@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+type Impersonation struct {
+	ID             string    `json:"id"`
+	TenantID       *string   `json:"tenant_id"`
+	UserID         string    `json:"user_id"`
+	ImpersonatorID string    `json:"impersonator_id"`
+	SessionID      string    `json:"session_id"`
+	Reason         string    `json:"reason"`
+	ClientIP       string    `json:"client_ip"`
+	UserAgent      string    `json:"user_agent"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// ImpersonationRequest is one API call made with an impersonation token.
+type ImpersonationRequest struct {
+	ID             string    `json:"id"`
+	SessionID      string    `json:"session_id"`
+	UserID         string    `json:"user_id"`
+	ImpersonatorID string    `json:"impersonator_id"`
+	Method         string    `json:"method"`
+	Path           string    `json:"path"`
+	Status         int       `json:"status"`
+	RequestID      string    `json:"request_id"`
+	ClientIP       string    `json:"client_ip"`
+	CreatedAt      time.Time `json:"created_at"`
+}
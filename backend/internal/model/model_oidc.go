@@ -0,0 +1,53 @@
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+// oidcRoleRank orders the roles an identity provider may grant, most
+// privileged first. Owner and system are never granted through SSO.
+var oidcRoleRank = []string{"admin", "doctor", "nurse", "technician", "reception"}
+
+type TenantOIDCProvider struct {
+	TenantID      string            `json:"tenant_id" gorm:"primaryKey"`
+	Issuer        string            `json:"issuer"`
+	ClientID      string            `json:"client_id"`
+	ClientSecret  string            `json:"-"`
+	RedirectURL   string            `json:"redirect_url"`
+	Scopes        []string          `json:"scopes" gorm:"serializer:json"`
+	UsernameClaim string            `json:"username_claim"`
+	NameClaim     string            `json:"name_claim"`
+	GroupsClaim   string            `json:"groups_claim"`
+	RoleMapping   map[string]string `json:"role_mapping" gorm:"serializer:json"`
+	DefaultRole   *string           `json:"default_role"`
+	AutoProvision bool              `json:"auto_provision"`
+	IsEnabled     bool              `json:"is_enabled"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+}
+
+// MapRole returns the most privileged role mapped from the user's groups, or
+// "" when none of them is mapped.
+func (p TenantOIDCProvider) MapRole(groups []string) string {
+	best := -1
+	for _, group := range groups {
+		rank := slices.Index(oidcRoleRank, p.RoleMapping[group])
+		if rank >= 0 && (best < 0 || rank < best) {
+			best = rank
+		}
+	}
+
+	if best < 0 {
+		return ""
+	}
+	return oidcRoleRank[best]
+}
+
+type UserIdentity struct {
+	ID        string    `json:"id"`
+	TenantID  string    `json:"tenant_id"`
+	UserID    string    `json:"user_id"`
+	Issuer    string    `json:"issuer"`
+	Subject   string    `json:"subject"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+type UserPasswordHistory struct {
+	ID           string    `json:"id"`
+	UserID       string    `json:"user_id"`
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type TenantPasswordPolicy struct {
+	TenantID      string    `json:"tenant_id"`
+	MinLength     int       `json:"min_length"`
+	RequireUpper  bool      `json:"require_upper"`
+	RequireLower  bool      `json:"require_lower"`
+	RequireDigit  bool      `json:"require_digit"`
+	RequireSymbol bool      `json:"require_symbol"`
+	HistorySize   int       `json:"history_size"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
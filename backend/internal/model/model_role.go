@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+// BuiltInRoles are the user_role values a tenant can grant permissions to.
+// The system role is not bound to a tenant and is never managed per tenant.
+var BuiltInRoles = []string{"owner", "admin", "doctor", "nurse", "technician", "reception"}
+
+// TenantRole is a custom role an owner created in addition to the built-in ones.
+type TenantRole struct {
+	ID          string    `json:"id"`
+	TenantID    string    `json:"tenant_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedBy   *string   `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// TenantPolicyTemplate records which version of a role template was last
+// applied to a tenant and the permissions it granted, so an upgrade only
+// changes what the template itself changed.
+type TenantPolicyTemplate struct {
+	TenantID    string    `json:"tenant_id"`
+	Role        string    `json:"role"`
+	Version     int       `json:"version"`
+	Permissions []string  `json:"permissions" gorm:"serializer:json"`
+	AppliedAt   time.Time `json:"applied_at"`
+}
+
+// TenantPolicies are the Casbin policies and role assignments a new tenant
+// starts with, and the template versions they come from.
+type TenantPolicies struct {
+	Policies  [][]string
+	Groupings [][]string
+	Templates []TenantPolicyTemplate
+}
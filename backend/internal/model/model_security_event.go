@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+type SecurityEvent struct {
+	ID                string    `json:"id"`
+	TenantID          *string   `json:"tenant_id"`
+	UserID            string    `json:"user_id"`
+	SessionID         string    `json:"session_id"`
+	Type              string    `json:"type"`
+	Action            string    `json:"action"`
+	ClientIP          string    `json:"client_ip"`
+	UserAgent         string    `json:"user_agent"`
+	PreviousClientIP  string    `json:"previous_client_ip"`
+	PreviousUserAgent string    `json:"previous_user_agent"`
+	CreatedAt         time.Time `json:"created_at"`
+}
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"slices"
+	"time"
+
+	"github.com/asliddinberdiev/eirsystem/pkg/permission"
+)
+
+type ServiceAccount struct {
+	ID          string    `json:"id"`
+	TenantID    string    `json:"tenant_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Role        string    `json:"role"`
+	IsActive    bool      `json:"is_active"`
+	CreatedBy   *string   `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type APIKey struct {
+	ID               string     `json:"id"`
+	TenantID         string     `json:"tenant_id"`
+	ServiceAccountID string     `json:"service_account_id"`
+	Name             string     `json:"name"`
+	Prefix           string     `json:"prefix"`
+	KeyHash          string     `json:"-"`
+	Scopes           []string   `json:"scopes" gorm:"serializer:json"`
+	ExpiresAt        *time.Time `json:"expires_at"`
+	LastUsedAt       *time.Time `json:"last_used_at"`
+	LastUsedIP       string     `json:"last_used_ip"`
+	RevokedAt        *time.Time `json:"revoked_at"`
+	CreatedBy        *string    `json:"created_by"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// IsExpired reports whether the key can no longer be used at the given moment.
+func (k APIKey) IsExpired(now time.Time) bool {
+	return k.RevokedAt != nil || (k.ExpiresAt != nil && !k.ExpiresAt.After(now))
+}
+
+// Allows reports whether the permission a route requires is inside the scopes
+// of the key. Each scope is a permission name, which may use "*" like a
+// policy. A key without scopes is limited only by the role of its service
+// account.
+func (k APIKey) Allows(name string) bool {
+	return len(k.Scopes) == 0 || slices.ContainsFunc(k.Scopes, func(scope string) bool {
+		return permission.Covers(scope, name)
+	})
+}
@@ -0,0 +1,12 @@
+package model
+
+// Subject is the caller a query runs for, as row-level access rules see it.
+// StaffID is the caller's staff profile, which appointments and lab orders
+// reference; it is empty for users without one.
+type Subject struct {
+	UserID   string      `json:"user_id"`
+	StaffID  string      `json:"staff_id,omitempty"`
+	TenantID string      `json:"tenant_id"`
+	Roles    []string    `json:"roles"`
+	Branches BranchScope `json:"branches"`
+}
@@ -0,0 +1,55 @@
+package model
+
+import "time"
+
+// Subscription states, from the point of view of the tenant's users.
+const (
+	SubscriptionActive    = "active"
+	SubscriptionGrace     = "grace"
+	SubscriptionExpired   = "expired"
+	SubscriptionSuspended = "suspended"
+)
+
+// Subscription actions recorded in the history of a tenant.
+const (
+	TenantRenewed     = "renewed"
+	TenantSuspended   = "suspended"
+	TenantReactivated = "reactivated"
+)
+
+// Subscription is the part of a tenant that decides whether its users may use
+// the API. It is cached per tenant.
+type Subscription struct {
+	TenantID string    `json:"tenant_id"`
+	IsActive bool      `json:"is_active"`
+	EndDate  time.Time `json:"end_date"`
+}
+
+// State tells what the subscription allows at the given moment: everything
+// until EndDate, reading for the grace period after it, and nothing once that
+// has passed too or while the tenant is suspended.
+func (s Subscription) State(now time.Time, grace time.Duration) string {
+	switch {
+	case !s.IsActive:
+		return SubscriptionSuspended
+	case now.Before(s.EndDate):
+		return SubscriptionActive
+	case now.Before(s.EndDate.Add(grace)):
+		return SubscriptionGrace
+	default:
+		return SubscriptionExpired
+	}
+}
+
+// TenantSubscriptionEvent records a renewal, suspension or reactivation of a
+// tenant's subscription by the system role.
+type TenantSubscriptionEvent struct {
+	ID              string    `json:"id"`
+	TenantID        string    `json:"tenant_id"`
+	Action          string    `json:"action"`
+	PreviousEndDate time.Time `json:"previous_end_date"`
+	EndDate         time.Time `json:"end_date"`
+	Reason          string    `json:"reason"`
+	ActorID         *string   `json:"actor_id"`
+	CreatedAt       time.Time `json:"created_at"`
+}
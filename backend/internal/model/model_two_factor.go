@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+type UserRecoveryCode struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"user_id"`
+	CodeHash  string     `json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type TenantTwoFactorRole struct {
+	TenantID string `json:"tenant_id"`
+	Role     string `json:"role"`
+}
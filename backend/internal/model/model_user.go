@@ -4,13 +4,31 @@ package model
 import "time"
 
 type User struct {
-	ID           string    `json:"id"`
-	TenantID     string    `json:"tenant_id"`
-	FullName     string    `json:"full_name"`
-	Username     string    `json:"username"`
-	PasswordHash string    `json:"-"`
-	Phone        string    `json:"phone"`
-	Role         string    `json:"role"`
-	IsActive     bool      `json:"is_active"`
-	CreatedAt    time.Time `json:"created_at"`
+	ID                 string     `json:"id"`
+	TenantID           string     `json:"tenant_id"`
+	FullName           string     `json:"full_name"`
+	Username           string     `json:"username"`
+	PasswordHash       string     `json:"-"`
+	Phone              string     `json:"phone"`
+	Role               string     `json:"role"`
+	IsActive           bool       `json:"is_active"`
+	TOTPSecret         string     `json:"-"`
+	TOTPEnabled        bool       `json:"totp_enabled"`
+	MustChangePassword bool       `json:"must_change_password"`
+	PasswordExpiresAt  *time.Time `json:"password_expires_at"`
+	PasswordChangedAt  *time.Time `json:"password_changed_at"`
+	AllowImpersonation bool       `json:"allow_impersonation"`
+	CreatedAt          time.Time  `json:"created_at"`
+}
+
+// StaffProfile holds the clinic details of a staff account. DisplayID is
+// assigned by the database.
+type StaffProfile struct {
+	ID              string  `json:"id"`
+	UserID          string  `json:"user_id"`
+	TenantID        string  `json:"tenant_id"`
+	DisplayID       string  `json:"display_id" gorm:"->"`
+	PrimaryBranchID *string `json:"primary_branch_id"`
+	Specialty       *string `json:"specialty"`
+	RoomNumber      *string `json:"room_number"`
 }
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+type UserBlock struct {
+	ID          string     `json:"id"`
+	TenantID    *string    `json:"tenant_id"`
+	UserID      string     `json:"user_id"`
+	BlockedBy   *string    `json:"blocked_by"`
+	Reason      string     `json:"reason"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	UnblockedBy *string    `json:"unblocked_by"`
+	UnblockedAt *time.Time `json:"unblocked_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// IsActive reports whether the block is still in force at the given moment.
+func (b UserBlock) IsActive(now time.Time) bool {
+	if b.UnblockedAt != nil {
+		return false
+	}
+	return b.ExpiresAt == nil || b.ExpiresAt.After(now)
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/asliddinberdiev/eirsystem/internal/model"
+	"gorm.io/gorm"
+)
+
+// Resources whose rows are filtered by the row-level access rules.
+const (
+	ResourcePatients     = "patients"
+	ResourceAppointments = "appointments"
+	ResourceLabOrders    = "lab_orders"
+	ResourcePayments     = "payments"
+)
+
+// rowRule returns the condition a row of the resource must meet for one of the
+// subject's roles. An empty condition means every row of the tenant.
+type rowRule func(resource string, s model.Subject) (string, []any)
+
+// rowRules are the row-level access rules: for each resource, the rows each
+// role may see. The route permission decides whether a caller may read the
+// resource at all; these rules decide which rows. A caller with several roles
+// sees the rows any of them allows. Roles not listed here, such as custom
+// roles, see the rows of their branches.
+var rowRules = map[string]map[string]rowRule{
+	ResourcePatients: {
+		"owner":      allRows,
+		"admin":      allRows,
+		"reception":  branchRows,
+		"doctor":     patientsOf(ResourceAppointments, "doctor_id"),
+		"technician": patientsOf(ResourceLabOrders, "technician_id"),
+		"nurse":      branchRows,
+	},
+	ResourceAppointments: {
+		"owner":     allRows,
+		"admin":     allRows,
+		"doctor":    ownRows("doctor_id"),
+		"nurse":     branchRows,
+		"reception": branchRows,
+	},
+	ResourceLabOrders: {
+		"owner":      allRows,
+		"admin":      allRows,
+		"doctor":     ownRows("doctor_id"),
+		"technician": ownRows("technician_id"),
+		"nurse":      branchRows,
+		"reception":  branchRows,
+	},
+	ResourcePayments: {
+		"owner":     allRows,
+		"admin":     allRows,
+		"reception": branchRows,
+	},
+}
+
+// Visible narrows a query on the resource to the rows the subject may see.
+// Repositories apply it with Scopes; handlers never build these conditions.
+func Visible(resource string, s model.Subject) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db = db.Where(resource+".tenant_id = ?", s.TenantID)
+		if slices.Contains(s.Roles, "system") {
+			return db
+		}
+
+		var (
+			conditions []string
+			args       []any
+		)
+		for _, role := range s.Roles {
+			rule, ok := rowRules[resource][role]
+			if !ok {
+				rule = branchRows
+			}
+
+			condition, conditionArgs := rule(resource, s)
+			if condition == "" {
+				return db
+			}
+			conditions = append(conditions, "("+condition+")")
+			args = append(args, conditionArgs...)
+		}
+
+		if len(conditions) == 0 {
+			return db.Where("1 = 0")
+		}
+		return db.Where("("+strings.Join(conditions, " OR ")+")", args...)
+	}
+}
+
+func allRows(string, model.Subject) (string, []any) {
+	return "", nil
+}
+
+// ownRows allows the rows that reference the subject's staff profile.
+func ownRows(column string) rowRule {
+	return func(resource string, s model.Subject) (string, []any) {
+		if s.StaffID == "" {
+			return "1 = 0", nil
+		}
+		return resource + "." + column + " = ?", []any{s.StaffID}
+	}
+}
+
+// patientsOf allows the patients that have a row in table referencing the
+// subject's staff profile.
+func patientsOf(table, column string) rowRule {
+	return func(resource string, s model.Subject) (string, []any) {
+		if s.StaffID == "" {
+			return "1 = 0", nil
+		}
+		return "EXISTS (SELECT 1 FROM " + table + " WHERE " + table + ".patient_id = " + resource + ".id AND " + table + "." + column + " = ?)", []any{s.StaffID}
+	}
+}
+
+// branchRows allows the rows of the branches the subject works in: its branch
+// roles' branches, or the active branch of a tenant-wide role. Patients belong
+// to no branch and are seen through their appointments there.
+func branchRows(resource string, s model.Subject) (string, []any) {
+	branchIDs := s.Branches.IDs
+	if s.Branches.All {
+		if s.Branches.Active == "" {
+			return "", nil
+		}
+		branchIDs = []string{s.Branches.Active}
+	}
+	if len(branchIDs) == 0 {
+		return "1 = 0", nil
+	}
+
+	if resource == ResourcePatients {
+		return "EXISTS (SELECT 1 FROM appointments WHERE appointments.patient_id = patients.id AND appointments.branch_id IN ?)", []any{branchIDs}
+	}
+	return resource + ".branch_id IN ?", []any{branchIDs}
+}
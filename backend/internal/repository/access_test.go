@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/asliddinberdiev/eirsystem/internal/model"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+const (
+	tenantID = "tenant"
+	staffID  = "staff"
+	branchA  = "branch-a"
+	branchB  = "branch-b"
+)
+
+// visibleSQL returns the query Visible builds on the resource, without a
+// database.
+func visibleSQL(t *testing.T, resource string, s model.Subject) (string, []any) {
+	t.Helper()
+
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stmt := db.Table(resource).Scopes(Visible(resource, s)).Find(&[]map[string]any{}).Statement
+	return stmt.SQL.String(), stmt.Vars
+}
+
+func TestVisible(t *testing.T) {
+	const (
+		appointmentsOfStaff = `EXISTS (SELECT 1 FROM appointments WHERE appointments.patient_id = patients.id AND appointments.doctor_id = $2)`
+		labOrdersOfStaff    = `EXISTS (SELECT 1 FROM lab_orders WHERE lab_orders.patient_id = patients.id AND lab_orders.technician_id = $2)`
+		patientsInBranches  = `EXISTS (SELECT 1 FROM appointments WHERE appointments.patient_id = patients.id AND appointments.branch_id IN ($2))`
+	)
+
+	tests := []struct {
+		name     string
+		resource string
+		subject  model.Subject
+		sql      string
+		vars     []any
+	}{
+		{
+			name:     "doctor sees the patients of its appointments",
+			resource: ResourcePatients,
+			subject:  model.Subject{TenantID: tenantID, StaffID: staffID, Roles: []string{"doctor"}},
+			sql:      `SELECT * FROM "patients" WHERE patients.tenant_id = $1 AND (((` + appointmentsOfStaff + `)))`,
+			vars:     []any{tenantID, staffID},
+		},
+		{
+			name:     "doctor sees its own appointments",
+			resource: ResourceAppointments,
+			subject:  model.Subject{TenantID: tenantID, StaffID: staffID, Roles: []string{"doctor"}},
+			sql:      `SELECT * FROM "appointments" WHERE appointments.tenant_id = $1 AND ((appointments.doctor_id = $2))`,
+			vars:     []any{tenantID, staffID},
+		},
+		{
+			name:     "doctor without a staff profile sees nothing",
+			resource: ResourceAppointments,
+			subject:  model.Subject{TenantID: tenantID, Roles: []string{"doctor"}},
+			sql:      `SELECT * FROM "appointments" WHERE appointments.tenant_id = $1 AND ((1 = 0))`,
+			vars:     []any{tenantID},
+		},
+		{
+			name:     "technician sees its assigned lab orders",
+			resource: ResourceLabOrders,
+			subject:  model.Subject{TenantID: tenantID, StaffID: staffID, Roles: []string{"technician"}},
+			sql:      `SELECT * FROM "lab_orders" WHERE lab_orders.tenant_id = $1 AND ((lab_orders.technician_id = $2))`,
+			vars:     []any{tenantID, staffID},
+		},
+		{
+			name:     "technician sees the patients of its lab orders",
+			resource: ResourcePatients,
+			subject:  model.Subject{TenantID: tenantID, StaffID: staffID, Roles: []string{"technician"}},
+			sql:      `SELECT * FROM "patients" WHERE patients.tenant_id = $1 AND (((` + labOrdersOfStaff + `)))`,
+			vars:     []any{tenantID, staffID},
+		},
+		{
+			name:     "technician has no payments rule and sees its branches",
+			resource: ResourcePayments,
+			subject:  model.Subject{TenantID: tenantID, StaffID: staffID, Roles: []string{"technician"}, Branches: model.BranchScope{IDs: []string{branchA}}},
+			sql:      `SELECT * FROM "payments" WHERE payments.tenant_id = $1 AND ((payments.branch_id IN ($2)))`,
+			vars:     []any{tenantID, branchA},
+		},
+		{
+			name:     "nurse sees the appointments of its branches",
+			resource: ResourceAppointments,
+			subject:  model.Subject{TenantID: tenantID, Roles: []string{"nurse"}, Branches: model.BranchScope{IDs: []string{branchA, branchB}}},
+			sql:      `SELECT * FROM "appointments" WHERE appointments.tenant_id = $1 AND ((appointments.branch_id IN ($2,$3)))`,
+			vars:     []any{tenantID, branchA, branchB},
+		},
+		{
+			name:     "tenant-wide nurse sees the active branch",
+			resource: ResourceAppointments,
+			subject:  model.Subject{TenantID: tenantID, Roles: []string{"nurse"}, Branches: model.BranchScope{All: true, Active: branchA}},
+			sql:      `SELECT * FROM "appointments" WHERE appointments.tenant_id = $1 AND ((appointments.branch_id IN ($2)))`,
+			vars:     []any{tenantID, branchA},
+		},
+		{
+			name:     "tenant-wide nurse without an active branch sees every branch",
+			resource: ResourceAppointments,
+			subject:  model.Subject{TenantID: tenantID, Roles: []string{"nurse"}, Branches: model.BranchScope{All: true}},
+			sql:      `SELECT * FROM "appointments" WHERE appointments.tenant_id = $1`,
+			vars:     []any{tenantID},
+		},
+		{
+			name:     "reception sees the patients of its branch",
+			resource: ResourcePatients,
+			subject:  model.Subject{TenantID: tenantID, Roles: []string{"reception"}, Branches: model.BranchScope{IDs: []string{branchA}}},
+			sql:      `SELECT * FROM "patients" WHERE patients.tenant_id = $1 AND (((` + patientsInBranches + `)))`,
+			vars:     []any{tenantID, branchA},
+		},
+		{
+			name:     "reception sees the payments of its branch",
+			resource: ResourcePayments,
+			subject:  model.Subject{TenantID: tenantID, Roles: []string{"reception"}, Branches: model.BranchScope{IDs: []string{branchA}}},
+			sql:      `SELECT * FROM "payments" WHERE payments.tenant_id = $1 AND ((payments.branch_id IN ($2)))`,
+			vars:     []any{tenantID, branchA},
+		},
+		{
+			name:     "reception without a branch sees nothing",
+			resource: ResourcePatients,
+			subject:  model.Subject{TenantID: tenantID, Roles: []string{"reception"}},
+			sql:      `SELECT * FROM "patients" WHERE patients.tenant_id = $1 AND ((1 = 0))`,
+			vars:     []any{tenantID},
+		},
+		{
+			name:     "custom role sees the rows of its branches",
+			resource: ResourceLabOrders,
+			subject:  model.Subject{TenantID: tenantID, Roles: []string{"lab-assistant"}, Branches: model.BranchScope{IDs: []string{branchB}}},
+			sql:      `SELECT * FROM "lab_orders" WHERE lab_orders.tenant_id = $1 AND ((lab_orders.branch_id IN ($2)))`,
+			vars:     []any{tenantID, branchB},
+		},
+		{
+			name:     "several roles see the rows any of them allows",
+			resource: ResourceAppointments,
+			subject:  model.Subject{TenantID: tenantID, StaffID: staffID, Roles: []string{"doctor", "reception"}, Branches: model.BranchScope{IDs: []string{branchA}}},
+			sql:      `SELECT * FROM "appointments" WHERE appointments.tenant_id = $1 AND (((appointments.doctor_id = $2) OR (appointments.branch_id IN ($3))))`,
+			vars:     []any{tenantID, staffID, branchA},
+		},
+		{
+			name:     "a role that sees every row wins over the others",
+			resource: ResourcePatients,
+			subject:  model.Subject{TenantID: tenantID, StaffID: staffID, Roles: []string{"doctor", "admin"}},
+			sql:      `SELECT * FROM "patients" WHERE patients.tenant_id = $1`,
+			vars:     []any{tenantID},
+		},
+		{
+			name:     "system sees every row of the tenant",
+			resource: ResourcePayments,
+			subject:  model.Subject{TenantID: tenantID, Roles: []string{"system"}},
+			sql:      `SELECT * FROM "payments" WHERE payments.tenant_id = $1`,
+			vars:     []any{tenantID},
+		},
+		{
+			name:     "no roles see nothing",
+			resource: ResourcePatients,
+			subject:  model.Subject{TenantID: tenantID},
+			sql:      `SELECT * FROM "patients" WHERE patients.tenant_id = $1 AND 1 = 0`,
+			vars:     []any{tenantID},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, vars := visibleSQL(t, tt.resource, tt.subject)
+			if sql != tt.sql {
+				t.Errorf("SQL:\n got %s\nwant %s", sql, tt.sql)
+			}
+			if !reflect.DeepEqual(vars, tt.vars) {
+				t.Errorf("vars: got %v, want %v", vars, tt.vars)
+			}
+		})
+	}
+}
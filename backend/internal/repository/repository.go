@@ -4,16 +4,50 @@ package repository
 import (
 	"github.com/asliddinberdiev/eirsystem/config"
 	"github.com/asliddinberdiev/eirsystem/pkg/logger"
+	"github.com/asliddinberdiev/eirsystem/pkg/postgres"
 	"github.com/asliddinberdiev/eirsystem/pkg/redis"
 	"gorm.io/gorm"
 )
 
 type Repository struct {
-	User User
+	Tenant         Tenant
+	User           User
+	UserBlock      UserBlock
+	TwoFactor      TwoFactor
+	SecurityEvent  SecurityEvent
+	LoginAttempt   LoginAttempt
+	Password       Password
+	ServiceAccount ServiceAccount
+	Impersonation  Impersonation
+	OIDC           OIDC
+	Role           Role
+	Branch         Branch
+	PolicyTemplate PolicyTemplate
 }
 
 func New(cfg *config.Config, logger logger.Logger, db *gorm.DB, rd *redis.RedisClient) *Repository {
 	return &Repository{
-		User: NewUserRepository(cfg, logger, db, rd),
+		Tenant:         NewTenantRepository(cfg, logger, db, rd),
+		User:           NewUserRepository(cfg, logger, db, rd),
+		UserBlock:      NewUserBlockRepository(cfg, logger, db),
+		TwoFactor:      NewTwoFactorRepository(cfg, logger, db, rd),
+		SecurityEvent:  NewSecurityEventRepository(cfg, logger, db),
+		LoginAttempt:   NewLoginAttemptRepository(cfg, logger, rd),
+		Password:       NewPasswordRepository(cfg, logger, db),
+		ServiceAccount: NewServiceAccountRepository(cfg, logger, db),
+		Impersonation:  NewImpersonationRepository(cfg, logger, db),
+		OIDC:           NewOIDCRepository(cfg, logger, db, rd),
+		Role:           NewRoleRepository(cfg, logger, db),
+		Branch:         NewBranchRepository(cfg, logger, db),
+		PolicyTemplate: NewPolicyTemplateRepository(cfg, logger, db),
 	}
-}
\ No newline at end of file
+}
+
+// tenantScope scopes a session to the tenant of a row; rows without a tenant
+// belong to system users and are written with the bypass.
+func tenantScope(db *gorm.DB, tenantID *string) *gorm.DB {
+	if tenantID == nil {
+		return postgres.Bypass(db)
+	}
+	return postgres.Tenant(db, *tenantID)
+}
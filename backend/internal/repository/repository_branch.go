@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"github.com/asliddinberdiev/eirsystem/config"
+	"github.com/asliddinberdiev/eirsystem/internal/model"
+	"github.com/asliddinberdiev/eirsystem/pkg/logger"
+	"github.com/asliddinberdiev/eirsystem/pkg/postgres"
+	"gorm.io/gorm"
+)
+
+type Branch interface {
+	GetAll(tenantID string) ([]model.Branch, error)
+	GetByID(tenantID, id string) (model.Branch, error)
+}
+
+type branchRepo struct {
+	cfg    *config.Config
+	logger logger.Logger
+	db     *gorm.DB
+}
+
+func NewBranchRepository(cfg *config.Config, logger logger.Logger, db *gorm.DB) Branch {
+	return &branchRepo{cfg: cfg, logger: logger, db: db}
+}
+
+func (r *branchRepo) GetAll(tenantID string) ([]model.Branch, error) {
+	var branches []model.Branch
+	return branches, postgres.Tenant(r.db, tenantID).Where("tenant_id = ?", tenantID).Order("name").Find(&branches).Error
+}
+
+func (r *branchRepo) GetByID(tenantID, id string) (model.Branch, error) {
+	var branch model.Branch
+	return branch, postgres.Tenant(r.db, tenantID).Where("tenant_id = ? AND id = ?", tenantID, id).First(&branch).Error
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"github.com/asliddinberdiev/eirsystem/config"
+	"github.com/asliddinberdiev/eirsystem/internal/model"
+	"github.com/asliddinberdiev/eirsystem/pkg/logger"
+	"github.com/asliddinberdiev/eirsystem/pkg/postgres"
+	"gorm.io/gorm"
+)
+
+type Impersonation interface {
+	Create(impersonation *model.Impersonation) error
+	CreateRequest(request *model.ImpersonationRequest) error
+	SetConsent(userID string, allow bool) error
+}
+
+type impersonationRepo struct {
+	cfg    *config.Config
+	logger logger.Logger
+	db     *gorm.DB
+}
+
+func NewImpersonationRepository(cfg *config.Config, logger logger.Logger, db *gorm.DB) Impersonation {
+	return &impersonationRepo{cfg: cfg, logger: logger, db: db}
+}
+
+func (r *impersonationRepo) Create(impersonation *model.Impersonation) error {
+	return tenantScope(r.db, impersonation.TenantID).Create(impersonation).Error
+}
+
+func (r *impersonationRepo) CreateRequest(request *model.ImpersonationRequest) error {
+	return r.db.Create(request).Error
+}
+
+func (r *impersonationRepo) SetConsent(userID string, allow bool) error {
+	return postgres.Bypass(r.db).Model(&model.User{}).Where("id = ?", userID).Update("allow_impersonation", allow).Error
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/asliddinberdiev/eirsystem/config"
+	"github.com/asliddinberdiev/eirsystem/pkg/logger"
+	"github.com/asliddinberdiev/eirsystem/pkg/redis"
+)
+
+type LoginAttempt interface {
+	GetLockout(ctx context.Context, scope, subject string) (time.Duration, error)
+	AddFailure(ctx context.Context, scope, subject string, window time.Duration) (int64, error)
+	Lock(ctx context.Context, scope, subject string, ttl time.Duration) error
+	Reset(ctx context.Context, scope, subject string) error
+}
+
+type loginAttemptRepo struct {
+	cfg    *config.Config
+	logger logger.Logger
+	rd     *redis.RedisClient
+}
+
+func NewLoginAttemptRepository(cfg *config.Config, logger logger.Logger, rd *redis.RedisClient) LoginAttempt {
+	return &loginAttemptRepo{cfg: cfg, logger: logger, rd: rd}
+}
+
+// GetLockout returns how long the subject stays locked out, or zero when it is not locked.
+func (r *loginAttemptRepo) GetLockout(ctx context.Context, scope, subject string) (time.Duration, error) {
+	ttl, err := r.rd.Client.PTTL(ctx, lockoutKey(scope, subject)).Result()
+	if err != nil {
+		return 0, err
+	}
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+// AddFailure counts a failed sign-in and returns the number of failures so far.
+// The counter is forgotten once no failure happened for the whole window.
+func (r *loginAttemptRepo) AddFailure(ctx context.Context, scope, subject string, window time.Duration) (int64, error) {
+	key := failureKey(scope, subject)
+
+	pipe := r.rd.Client.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+func (r *loginAttemptRepo) Lock(ctx context.Context, scope, subject string, ttl time.Duration) error {
+	return r.rd.Client.Set(ctx, lockoutKey(scope, subject), 1, ttl).Err()
+}
+
+func (r *loginAttemptRepo) Reset(ctx context.Context, scope, subject string) error {
+	return r.rd.Client.Del(ctx, failureKey(scope, subject), lockoutKey(scope, subject)).Err()
+}
+
+func failureKey(scope, subject string) string {
+	return fmt.Sprintf("auth:failures:%s:%s", scope, subject)
+}
+
+func lockoutKey(scope, subject string) string {
+	return fmt.Sprintf("auth:lockout:%s:%s", scope, subject)
+}
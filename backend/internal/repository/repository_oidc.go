@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/asliddinberdiev/eirsystem/config"
+	"github.com/asliddinberdiev/eirsystem/internal/model"
+	"github.com/asliddinberdiev/eirsystem/pkg/logger"
+	"github.com/asliddinberdiev/eirsystem/pkg/postgres"
+	"github.com/asliddinberdiev/eirsystem/pkg/redis"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OIDCState is what the authorization request leaves behind for the callback.
+type OIDCState struct {
+	TenantID     string `json:"tenant_id"`
+	CodeVerifier string `json:"code_verifier"`
+	Nonce        string `json:"nonce"`
+}
+
+type OIDC interface {
+	GetProvider(tenantID string) (model.TenantOIDCProvider, error)
+	SetProvider(provider *model.TenantOIDCProvider) error
+	DeleteProvider(tenantID string) error
+
+	GetIdentity(tenantID, issuer, subject string) (model.UserIdentity, error)
+	CreateUser(user *model.User, identity *model.UserIdentity) error
+	UpdateRole(userID, role string) error
+
+	SaveState(ctx context.Context, state string, data OIDCState, ttl time.Duration) error
+	TakeState(ctx context.Context, state string) (OIDCState, bool, error)
+}
+
+type oidcRepo struct {
+	cfg    *config.Config
+	logger logger.Logger
+	db     *gorm.DB
+	rd     *redis.RedisClient
+}
+
+func NewOIDCRepository(cfg *config.Config, logger logger.Logger, db *gorm.DB, rd *redis.RedisClient) OIDC {
+	return &oidcRepo{cfg: cfg, logger: logger, db: db, rd: rd}
+}
+
+func (r *oidcRepo) GetProvider(tenantID string) (model.TenantOIDCProvider, error) {
+	var provider model.TenantOIDCProvider
+	return provider, postgres.Tenant(r.db, tenantID).Where("tenant_id = ?", tenantID).Take(&provider).Error
+}
+
+func (r *oidcRepo) SetProvider(provider *model.TenantOIDCProvider) error {
+	provider.UpdatedAt = time.Now()
+	return postgres.Tenant(r.db, provider.TenantID).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tenant_id"}},
+		UpdateAll: true,
+	}).Create(provider).Error
+}
+
+func (r *oidcRepo) DeleteProvider(tenantID string) error {
+	return postgres.Tenant(r.db, tenantID).Where("tenant_id = ?", tenantID).Delete(&model.TenantOIDCProvider{}).Error
+}
+
+func (r *oidcRepo) GetIdentity(tenantID, issuer, subject string) (model.UserIdentity, error) {
+	var identity model.UserIdentity
+	return identity, postgres.Tenant(r.db, tenantID).Where("tenant_id = ? AND issuer = ? AND subject = ?", tenantID, issuer, subject).Take(&identity).Error
+}
+
+// CreateUser provisions a user signed in through SSO together with the link
+// to its identity, so a half-created user never exists.
+func (r *oidcRepo) CreateUser(user *model.User, identity *model.UserIdentity) error {
+	return postgres.Tenant(r.db, identity.TenantID).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		return tx.Create(identity).Error
+	})
+}
+
+func (r *oidcRepo) UpdateRole(userID, role string) error {
+	return postgres.Bypass(r.db).Model(&model.User{}).Where("id = ?", userID).Update("role", role).Error
+}
+
+func (r *oidcRepo) SaveState(ctx context.Context, state string, data OIDCState, ttl time.Duration) error {
+	return r.rd.Set(ctx, oidcStateKey(state), data, ttl)
+}
+
+// TakeState returns the state and deletes it, so a callback can't be replayed.
+func (r *oidcRepo) TakeState(ctx context.Context, state string) (OIDCState, bool, error) {
+	val, err := r.rd.Client.GetDel(ctx, oidcStateKey(state)).Result()
+	if errors.Is(err, redis.Nil) {
+		return OIDCState{}, false, nil
+	}
+	if err != nil {
+		return OIDCState{}, false, err
+	}
+
+	var data OIDCState
+	return data, true, json.Unmarshal([]byte(val), &data)
+}
+
+func oidcStateKey(state string) string {
+	return "auth:oidc:state:" + state
+}
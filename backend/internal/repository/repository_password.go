@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/asliddinberdiev/eirsystem/config"
+	"github.com/asliddinberdiev/eirsystem/internal/model"
+	"github.com/asliddinberdiev/eirsystem/pkg/logger"
+	"github.com/asliddinberdiev/eirsystem/pkg/postgres"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxPasswordHistory bounds the stored history, the largest policy a tenant may set.
+const maxPasswordHistory = 24
+
+type Password interface {
+	Update(userID, passwordHash string, expiresAt *time.Time) error
+	SetHash(userID, passwordHash string) error
+	GetHistory(userID string, limit int) ([]string, error)
+
+	GetPolicy(tenantID string) (model.TenantPasswordPolicy, error)
+	SetPolicy(policy *model.TenantPasswordPolicy) error
+}
+
+type passwordRepo struct {
+	cfg    *config.Config
+	logger logger.Logger
+	db     *gorm.DB
+}
+
+func NewPasswordRepository(cfg *config.Config, logger logger.Logger, db *gorm.DB) Password {
+	return &passwordRepo{cfg: cfg, logger: logger, db: db}
+}
+
+// Update replaces the password of the user. A non-nil expiresAt marks it as a
+// temporary password that must be changed before it expires; temporary
+// passwords are not kept in the history.
+func (r *passwordRepo) Update(userID, passwordHash string, expiresAt *time.Time) error {
+	return postgres.Bypass(r.db).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.User{}).Where("id = ?", userID).Updates(map[string]any{
+			"password_hash":        passwordHash,
+			"must_change_password": expiresAt != nil,
+			"password_expires_at":  expiresAt,
+			"password_changed_at":  time.Now(),
+		}).Error
+		if err != nil {
+			return err
+		}
+		if expiresAt != nil {
+			return nil
+		}
+
+		entry := model.UserPasswordHistory{
+			ID:           uuid.New().String(),
+			UserID:       userID,
+			PasswordHash: passwordHash,
+		}
+		if err := tx.Create(&entry).Error; err != nil {
+			return err
+		}
+
+		return tx.Exec(`
+			DELETE FROM user_password_histories
+			WHERE user_id = ? AND id NOT IN (
+				SELECT id FROM user_password_histories WHERE user_id = ? ORDER BY created_at DESC LIMIT ?
+			)`, userID, userID, maxPasswordHistory).Error
+	})
+}
+
+// SetHash stores a new hash of the same password, leaving its expiry and the
+// history untouched.
+func (r *passwordRepo) SetHash(userID, passwordHash string) error {
+	return postgres.Bypass(r.db).Model(&model.User{}).Where("id = ?", userID).Update("password_hash", passwordHash).Error
+}
+
+// GetHistory returns the hashes of the last limit passwords of the user, newest first.
+func (r *passwordRepo) GetHistory(userID string, limit int) ([]string, error) {
+	var hashes []string
+	return hashes, r.db.Model(&model.UserPasswordHistory{}).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(limit).
+		Pluck("password_hash", &hashes).Error
+}
+
+func (r *passwordRepo) GetPolicy(tenantID string) (model.TenantPasswordPolicy, error) {
+	var policy model.TenantPasswordPolicy
+	return policy, postgres.Tenant(r.db, tenantID).Where("tenant_id = ?", tenantID).Take(&policy).Error
+}
+
+func (r *passwordRepo) SetPolicy(policy *model.TenantPasswordPolicy) error {
+	policy.UpdatedAt = time.Now()
+	return postgres.Tenant(r.db, policy.TenantID).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tenant_id"}},
+		UpdateAll: true,
+	}).Create(policy).Error
+}
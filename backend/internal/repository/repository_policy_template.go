@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/asliddinberdiev/eirsystem/config"
+	"github.com/asliddinberdiev/eirsystem/internal/model"
+	"github.com/asliddinberdiev/eirsystem/pkg/logger"
+	"github.com/asliddinberdiev/eirsystem/pkg/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PolicyTemplate interface {
+	GetAll(tenantID string) ([]model.TenantPolicyTemplate, error)
+	Save(template *model.TenantPolicyTemplate) error
+}
+
+type policyTemplateRepo struct {
+	cfg    *config.Config
+	logger logger.Logger
+	db     *gorm.DB
+}
+
+func NewPolicyTemplateRepository(cfg *config.Config, logger logger.Logger, db *gorm.DB) PolicyTemplate {
+	return &policyTemplateRepo{cfg: cfg, logger: logger, db: db}
+}
+
+func (r *policyTemplateRepo) GetAll(tenantID string) ([]model.TenantPolicyTemplate, error) {
+	var templates []model.TenantPolicyTemplate
+	return templates, postgres.Tenant(r.db, tenantID).Where("tenant_id = ?", tenantID).Find(&templates).Error
+}
+
+func (r *policyTemplateRepo) Save(template *model.TenantPolicyTemplate) error {
+	template.AppliedAt = time.Now()
+	return postgres.Tenant(r.db, template.TenantID).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tenant_id"}, {Name: "role"}},
+		UpdateAll: true,
+	}).Create(template).Error
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"github.com/asliddinberdiev/eirsystem/config"
+	"github.com/asliddinberdiev/eirsystem/internal/model"
+	"github.com/asliddinberdiev/eirsystem/pkg/logger"
+	"github.com/asliddinberdiev/eirsystem/pkg/postgres"
+	"gorm.io/gorm"
+)
+
+type Role interface {
+	GetAll(tenantID string) ([]model.TenantRole, error)
+	Exists(tenantID, name string) (bool, error)
+	Create(role *model.TenantRole) error
+	Delete(tenantID, name string) error
+}
+
+type roleRepo struct {
+	cfg    *config.Config
+	logger logger.Logger
+	db     *gorm.DB
+}
+
+func NewRoleRepository(cfg *config.Config, logger logger.Logger, db *gorm.DB) Role {
+	return &roleRepo{cfg: cfg, logger: logger, db: db}
+}
+
+func (r *roleRepo) GetAll(tenantID string) ([]model.TenantRole, error) {
+	var roles []model.TenantRole
+	return roles, postgres.Tenant(r.db, tenantID).Where("tenant_id = ?", tenantID).Order("name").Find(&roles).Error
+}
+
+func (r *roleRepo) Exists(tenantID, name string) (bool, error) {
+	var count int64
+	err := postgres.Tenant(r.db, tenantID).Model(&model.TenantRole{}).Where("tenant_id = ? AND name = ?", tenantID, name).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *roleRepo) Create(role *model.TenantRole) error {
+	return postgres.Tenant(r.db, role.TenantID).Create(role).Error
+}
+
+func (r *roleRepo) Delete(tenantID, name string) error {
+	return postgres.Tenant(r.db, tenantID).Where("tenant_id = ? AND name = ?", tenantID, name).Delete(&model.TenantRole{}).Error
+}
@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"github.com/asliddinberdiev/eirsystem/config"
+	"github.com/asliddinberdiev/eirsystem/internal/model"
+	"github.com/asliddinberdiev/eirsystem/pkg/logger"
+	"gorm.io/gorm"
+)
+
+type SecurityEvent interface {
+	Create(event *model.SecurityEvent) error
+}
+
+type securityEventRepo struct {
+	cfg    *config.Config
+	logger logger.Logger
+	db     *gorm.DB
+}
+
+func NewSecurityEventRepository(cfg *config.Config, logger logger.Logger, db *gorm.DB) SecurityEvent {
+	return &securityEventRepo{cfg: cfg, logger: logger, db: db}
+}
+
+func (r *securityEventRepo) Create(event *model.SecurityEvent) error {
+	return tenantScope(r.db, event.TenantID).Create(event).Error
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/asliddinberdiev/eirsystem/config"
+	"github.com/asliddinberdiev/eirsystem/internal/model"
+	"github.com/asliddinberdiev/eirsystem/pkg/logger"
+	"github.com/asliddinberdiev/eirsystem/pkg/postgres"
+	"gorm.io/gorm"
+)
+
+type ServiceAccount interface {
+	Create(account *model.ServiceAccount) error
+	GetAll(tenantID string) ([]model.ServiceAccount, error)
+	GetByID(tenantID, id string) (model.ServiceAccount, error)
+	ExistsByName(tenantID, name string) (bool, error)
+	Delete(tenantID, id string) error
+
+	CreateKey(key *model.APIKey) error
+	GetKeys(serviceAccountID string) ([]model.APIKey, error)
+	GetKeyByID(serviceAccountID, id string) (model.APIKey, error)
+	GetKeyByPrefix(prefix string) (model.APIKey, error)
+	RevokeKey(id string) error
+	RotateKey(id string, oldExpiresAt time.Time, key *model.APIKey) error
+	TouchKey(id, clientIP string) error
+}
+
+type serviceAccountRepo struct {
+	cfg    *config.Config
+	logger logger.Logger
+	db     *gorm.DB
+}
+
+func NewServiceAccountRepository(cfg *config.Config, logger logger.Logger, db *gorm.DB) ServiceAccount {
+	return &serviceAccountRepo{cfg: cfg, logger: logger, db: db}
+}
+
+func (r *serviceAccountRepo) Create(account *model.ServiceAccount) error {
+	return postgres.Tenant(r.db, account.TenantID).Create(account).Error
+}
+
+func (r *serviceAccountRepo) GetAll(tenantID string) ([]model.ServiceAccount, error) {
+	var accounts []model.ServiceAccount
+	return accounts, postgres.Tenant(r.db, tenantID).Where("tenant_id = ?", tenantID).Order("created_at").Find(&accounts).Error
+}
+
+func (r *serviceAccountRepo) GetByID(tenantID, id string) (model.ServiceAccount, error) {
+	var account model.ServiceAccount
+	return account, postgres.Tenant(r.db, tenantID).Where("tenant_id = ? AND id = ?", tenantID, id).Take(&account).Error
+}
+
+func (r *serviceAccountRepo) ExistsByName(tenantID, name string) (bool, error) {
+	var count int64
+	err := postgres.Tenant(r.db, tenantID).Model(&model.ServiceAccount{}).Where("tenant_id = ? AND name = ?", tenantID, name).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *serviceAccountRepo) Delete(tenantID, id string) error {
+	result := postgres.Tenant(r.db, tenantID).Where("tenant_id = ? AND id = ?", tenantID, id).Delete(&model.ServiceAccount{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *serviceAccountRepo) CreateKey(key *model.APIKey) error {
+	return postgres.Tenant(r.db, key.TenantID).Create(key).Error
+}
+
+func (r *serviceAccountRepo) GetKeys(serviceAccountID string) ([]model.APIKey, error) {
+	var keys []model.APIKey
+	return keys, postgres.Bypass(r.db).Where("service_account_id = ?", serviceAccountID).Order("created_at DESC").Find(&keys).Error
+}
+
+func (r *serviceAccountRepo) GetKeyByID(serviceAccountID, id string) (model.APIKey, error) {
+	var key model.APIKey
+	return key, postgres.Bypass(r.db).Where("service_account_id = ? AND id = ?", serviceAccountID, id).Take(&key).Error
+}
+
+// GetKeyByPrefix finds the key of an API request, before its tenant is known.
+func (r *serviceAccountRepo) GetKeyByPrefix(prefix string) (model.APIKey, error) {
+	var key model.APIKey
+	return key, postgres.Bypass(r.db).Where("prefix = ?", prefix).Take(&key).Error
+}
+
+func (r *serviceAccountRepo) RevokeKey(id string) error {
+	return postgres.Bypass(r.db).Model(&model.APIKey{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", time.Now()).Error
+}
+
+// RotateKey stores the replacement key and cuts the old one down to a short
+// grace period, so integrations can switch over without downtime.
+func (r *serviceAccountRepo) RotateKey(id string, oldExpiresAt time.Time, key *model.APIKey) error {
+	return postgres.Tenant(r.db, key.TenantID).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.APIKey{}).
+			Where("id = ? AND (expires_at IS NULL OR expires_at > ?)", id, oldExpiresAt).
+			Update("expires_at", oldExpiresAt).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(key).Error
+	})
+}
+
+func (r *serviceAccountRepo) TouchKey(id, clientIP string) error {
+	return postgres.Bypass(r.db).Model(&model.APIKey{}).Where("id = ?", id).
+		Updates(map[string]any{"last_used_at": time.Now(), "last_used_ip": clientIP}).Error
+}
@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/asliddinberdiev/eirsystem/config"
+	"github.com/asliddinberdiev/eirsystem/internal/model"
+	"github.com/asliddinberdiev/eirsystem/pkg/logger"
+	"github.com/asliddinberdiev/eirsystem/pkg/postgres"
+	"github.com/asliddinberdiev/eirsystem/pkg/redis"
+	gormadapter "github.com/casbin/gorm-adapter/v3"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Tenant interface {
+	GetBySlug(slug string) (model.Tenant, error)
+	GetAllIDs() ([]string, error)
+	Create(tenant *model.Tenant, branch *model.Branch, owner *model.User, staff *model.StaffProfile, policies model.TenantPolicies) error
+
+	GetByID(id string) (model.Tenant, error)
+	GetActiveEndingBefore(before time.Time) ([]model.Tenant, error)
+	GetCachedSubscription(ctx context.Context, id string) (model.Subscription, error)
+	DeleteSubscriptionCache(ctx context.Context, id string) error
+	UpdateSubscription(tenant *model.Tenant, event *model.TenantSubscriptionEvent) error
+	GetSubscriptionEvents(tenantID string) ([]model.TenantSubscriptionEvent, error)
+	MarkReminderSent(ctx context.Context, key string, ttl time.Duration) (bool, error)
+}
+
+const subscriptionCacheTTL = time.Minute
+
+type tenantRepo struct {
+	cfg    *config.Config
+	logger logger.Logger
+	db     *gorm.DB
+	rd     *redis.RedisClient
+}
+
+func NewTenantRepository(cfg *config.Config, logger logger.Logger, db *gorm.DB, rd *redis.RedisClient) Tenant {
+	return &tenantRepo{cfg: cfg, logger: logger, db: db, rd: rd}
+}
+
+func (r *tenantRepo) GetBySlug(slug string) (model.Tenant, error) {
+	var tenant model.Tenant
+	return tenant, r.db.Where("slug = ?", slug).Take(&tenant).Error
+}
+
+func (r *tenantRepo) GetAllIDs() ([]string, error) {
+	var ids []string
+	return ids, r.db.Model(&model.Tenant{}).Order("created_at").Pluck("id", &ids).Error
+}
+
+// Create stores a new tenant together with its first branch, its owner's
+// account and staff profile, and its default policies, and links the owner to
+// the tenant. A taken slug fails with gorm.ErrDuplicatedKey.
+func (r *tenantRepo) Create(tenant *model.Tenant, branch *model.Branch, owner *model.User, staff *model.StaffProfile, policies model.TenantPolicies) error {
+	err := postgres.Tenant(r.db, tenant.ID).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(tenant).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(branch).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(owner).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(staff).Error; err != nil {
+			return err
+		}
+
+		tenant.OwnerID = &owner.ID
+		if err := tx.Model(tenant).Update("owner_id", owner.ID).Error; err != nil {
+			return err
+		}
+
+		// Rows as the Casbin adapter writes them, so its unique index makes
+		// loading them into the enforcer afterwards a no-op.
+		rules := append(casbinRules("p", policies.Policies), casbinRules("g", policies.Groupings)...)
+		if len(rules) > 0 {
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rules).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(policies.Templates) > 0 {
+			now := time.Now()
+			for i := range policies.Templates {
+				policies.Templates[i].AppliedAt = now
+			}
+			return tx.Create(&policies.Templates).Error
+		}
+		return nil
+	})
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "tenants_slug_key" {
+		return gorm.ErrDuplicatedKey
+	}
+	return err
+}
+
+func casbinRules(ptype string, rules [][]string) []gormadapter.CasbinRule {
+	lines := make([]gormadapter.CasbinRule, 0, len(rules))
+	for _, rule := range rules {
+		line := gormadapter.CasbinRule{Ptype: ptype}
+		values := []*string{&line.V0, &line.V1, &line.V2, &line.V3, &line.V4, &line.V5}
+		for i, value := range rule {
+			*values[i] = value
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func (r *tenantRepo) GetByID(id string) (model.Tenant, error) {
+	var tenant model.Tenant
+	return tenant, r.db.Where("id = ?", id).Take(&tenant).Error
+}
+
+// GetActiveEndingBefore lists the tenants that are not suspended and whose
+// subscription ends before the given moment, including ended ones.
+func (r *tenantRepo) GetActiveEndingBefore(before time.Time) ([]model.Tenant, error) {
+	var tenants []model.Tenant
+	return tenants, r.db.Where("is_active AND subscription_end_date < ?", before).Order("subscription_end_date").Find(&tenants).Error
+}
+
+// GetCachedSubscription returns the subscription from Redis, falling back to
+// Postgres on a miss. Every request of a tenant user reads it.
+func (r *tenantRepo) GetCachedSubscription(ctx context.Context, id string) (model.Subscription, error) {
+	var subscription model.Subscription
+	if err := r.rd.Get(ctx, subscriptionCacheKey(id), &subscription); err == nil {
+		return subscription, nil
+	}
+
+	tenant, err := r.GetByID(id)
+	if err != nil {
+		return subscription, err
+	}
+
+	subscription = model.Subscription{TenantID: tenant.ID, IsActive: tenant.IsActive, EndDate: tenant.SubscriptionEndDate}
+	if err := r.rd.Set(ctx, subscriptionCacheKey(id), subscription, subscriptionCacheTTL); err != nil {
+		r.logger.Warn("subscription cache set failed", logger.String("tenant_id", id), logger.Error(err))
+	}
+
+	return subscription, nil
+}
+
+func (r *tenantRepo) DeleteSubscriptionCache(ctx context.Context, id string) error {
+	return r.rd.Delete(ctx, subscriptionCacheKey(id))
+}
+
+// UpdateSubscription stores the new subscription of the tenant and the event
+// that changed it.
+func (r *tenantRepo) UpdateSubscription(tenant *model.Tenant, event *model.TenantSubscriptionEvent) error {
+	return postgres.Tenant(r.db, tenant.ID).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(tenant).Updates(map[string]any{
+			"is_active":             tenant.IsActive,
+			"subscription_end_date": tenant.SubscriptionEndDate,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(event).Error
+	})
+}
+
+func (r *tenantRepo) GetSubscriptionEvents(tenantID string) ([]model.TenantSubscriptionEvent, error) {
+	var events []model.TenantSubscriptionEvent
+	return events, postgres.Tenant(r.db, tenantID).Where("tenant_id = ?", tenantID).Order("created_at DESC").Find(&events).Error
+}
+
+// MarkReminderSent records that a reminder went out and reports whether it was
+// the first time, so that only one instance sends it.
+func (r *tenantRepo) MarkReminderSent(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	return r.rd.Client.SetNX(ctx, "tenant:subscription:reminder:"+key, 1, ttl).Result()
+}
+
+func subscriptionCacheKey(id string) string {
+	return "tenant:subscription:" + id
+}
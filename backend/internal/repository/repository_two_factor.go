@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/asliddinberdiev/eirsystem/config"
+	"github.com/asliddinberdiev/eirsystem/internal/model"
+	"github.com/asliddinberdiev/eirsystem/pkg/logger"
+	"github.com/asliddinberdiev/eirsystem/pkg/postgres"
+	"github.com/asliddinberdiev/eirsystem/pkg/redis"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type TwoFactor interface {
+	SetPendingSecret(ctx context.Context, userID, secret string, ttl time.Duration) error
+	GetPendingSecret(ctx context.Context, userID string) (string, error)
+	MarkCodeUsed(ctx context.Context, userID string, counter int64, ttl time.Duration) (bool, error)
+
+	Enable(userID, secret string, codeHashes []string) error
+	Disable(userID string) error
+	ReplaceRecoveryCodes(userID string, codeHashes []string) error
+	UseRecoveryCode(userID, codeHash string) (bool, error)
+
+	GetRequiredRoles(tenantID string) ([]string, error)
+	SetRequiredRoles(tenantID string, roles []string) error
+}
+
+type twoFactorRepo struct {
+	cfg    *config.Config
+	logger logger.Logger
+	db     *gorm.DB
+	rd     *redis.RedisClient
+}
+
+func NewTwoFactorRepository(cfg *config.Config, logger logger.Logger, db *gorm.DB, rd *redis.RedisClient) TwoFactor {
+	return &twoFactorRepo{cfg: cfg, logger: logger, db: db, rd: rd}
+}
+
+func (r *twoFactorRepo) SetPendingSecret(ctx context.Context, userID, secret string, ttl time.Duration) error {
+	return r.rd.Set(ctx, pendingSecretKey(userID), secret, ttl)
+}
+
+func (r *twoFactorRepo) GetPendingSecret(ctx context.Context, userID string) (string, error) {
+	var secret string
+	return secret, r.rd.Get(ctx, pendingSecretKey(userID), &secret)
+}
+
+// MarkCodeUsed records the TOTP time counter as spent and reports false when
+// it was already spent, which rejects a replay of the same code.
+func (r *twoFactorRepo) MarkCodeUsed(ctx context.Context, userID string, counter int64, ttl time.Duration) (bool, error) {
+	key := fmt.Sprintf("user:%s:totp:used:%d", userID, counter)
+	return r.rd.Client.SetNX(ctx, key, 1, ttl).Result()
+}
+
+func (r *twoFactorRepo) Enable(userID, secret string, codeHashes []string) error {
+	return postgres.Bypass(r.db).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.User{}).Where("id = ?", userID).
+			Updates(map[string]any{"totp_secret": secret, "totp_enabled": true}).Error
+		if err != nil {
+			return err
+		}
+		return replaceRecoveryCodes(tx, userID, codeHashes)
+	})
+}
+
+func (r *twoFactorRepo) Disable(userID string) error {
+	return postgres.Bypass(r.db).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.User{}).Where("id = ?", userID).
+			Updates(map[string]any{"totp_secret": nil, "totp_enabled": false}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&model.UserRecoveryCode{}).Error
+	})
+}
+
+func (r *twoFactorRepo) ReplaceRecoveryCodes(userID string, codeHashes []string) error {
+	return postgres.Bypass(r.db).Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, codeHashes)
+	})
+}
+
+func (r *twoFactorRepo) UseRecoveryCode(userID, codeHash string) (bool, error) {
+	res := r.db.Model(&model.UserRecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *twoFactorRepo) GetRequiredRoles(tenantID string) ([]string, error) {
+	roles := []string{}
+	return roles, postgres.Tenant(r.db, tenantID).Model(&model.TenantTwoFactorRole{}).
+		Where("tenant_id = ?", tenantID).
+		Order("role").
+		Pluck("role", &roles).Error
+}
+
+func (r *twoFactorRepo) SetRequiredRoles(tenantID string, roles []string) error {
+	return postgres.Tenant(r.db, tenantID).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tenant_id = ?", tenantID).Delete(&model.TenantTwoFactorRole{}).Error; err != nil {
+			return err
+		}
+		if len(roles) == 0 {
+			return nil
+		}
+
+		rows := make([]model.TenantTwoFactorRole, 0, len(roles))
+		for _, role := range roles {
+			rows = append(rows, model.TenantTwoFactorRole{TenantID: tenantID, Role: role})
+		}
+		return tx.Create(&rows).Error
+	})
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID string, codeHashes []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&model.UserRecoveryCode{}).Error; err != nil {
+		return err
+	}
+	if len(codeHashes) == 0 {
+		return errors.New("no recovery codes to store")
+	}
+
+	rows := make([]model.UserRecoveryCode, 0, len(codeHashes))
+	for _, hash := range codeHashes {
+		rows = append(rows, model.UserRecoveryCode{ID: uuid.New().String(), UserID: userID, CodeHash: hash})
+	}
+	return tx.Create(&rows).Error
+}
+
+func pendingSecretKey(userID string) string {
+	return fmt.Sprintf("user:%s:totp:pending", userID)
+}
@@ -1,19 +1,30 @@
 package repository
 
 import (
+	"context"
+	"time"
+
 	"github.com/asliddinberdiev/eirsystem/config"
 	"github.com/asliddinberdiev/eirsystem/internal/model"
 	"github.com/asliddinberdiev/eirsystem/pkg/logger"
+	"github.com/asliddinberdiev/eirsystem/pkg/postgres"
 	"github.com/asliddinberdiev/eirsystem/pkg/redis"
 	"gorm.io/gorm"
 )
 
 type User interface {
-	GetAll() ([]model.User, error)
+	GetAll(tenantID string) ([]model.User, error)
+	GetAllTenants() ([]model.User, error)
 	GetByID(id string) (model.User, error)
-	GetByUsername(username string) (model.User, error)
+	GetByUsername(tenantID, username string) (model.User, error)
+	GetSystemByUsername(username string) (model.User, error)
+	GetStaffID(tenantID, userID string) (string, error)
+	GetCachedByID(ctx context.Context, id string) (model.User, error)
+	DeleteCache(ctx context.Context, id string) error
 }
 
+const userCacheTTL = time.Minute
+
 type userRepo struct {
 	cfg    *config.Config
 	logger logger.Logger
@@ -25,17 +36,69 @@ func NewUserRepository(cfg *config.Config, logger logger.Logger, db *gorm.DB, rd
 	return &userRepo{cfg: cfg, logger: logger, db: db, rd: rd}
 }
 
-func (r *userRepo) GetAll() ([]model.User, error) {
+func (r *userRepo) GetAll(tenantID string) ([]model.User, error) {
+	var users []model.User
+	return users, postgres.Tenant(r.db, tenantID).Where("tenant_id = ?", tenantID).Find(&users).Error
+}
+
+// GetAllTenants lists the users of every tenant, for system callers.
+func (r *userRepo) GetAllTenants() ([]model.User, error) {
 	var users []model.User
-	return users, r.db.Find(&users).Error
+	return users, postgres.Bypass(r.db).Find(&users).Error
 }
 
+// GetByID looks the user up across tenants: it is how the tenant of a token
+// or a managed user is found. Callers check the tenant of the result.
 func (r *userRepo) GetByID(id string) (model.User, error) {
 	var user model.User
-	return user, r.db.Where("id = ?", id).Take(&user).Error
+	return user, postgres.Bypass(r.db).Where("id = ?", id).Take(&user).Error
+}
+
+// GetByUsername finds a tenant user; system accounts sign in through GetSystemByUsername.
+func (r *userRepo) GetByUsername(tenantID, username string) (model.User, error) {
+	var user model.User
+	return user, postgres.Tenant(r.db, tenantID).Where("tenant_id = ? AND username = ? AND role <> 'system'", tenantID, username).Take(&user).Error
+}
+
+func (r *userRepo) GetSystemByUsername(username string) (model.User, error) {
+	var user model.User
+	return user, postgres.Bypass(r.db).Where("username = ? AND role = 'system'", username).Take(&user).Error
+}
+
+// GetStaffID returns the id of the user's staff profile, or "" if it has none.
+func (r *userRepo) GetStaffID(tenantID, userID string) (string, error) {
+	var ids []string
+	err := postgres.Tenant(r.db, tenantID).Table("staff_profiles").Where("tenant_id = ? AND user_id = ?", tenantID, userID).Limit(1).Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return "", err
+	}
+	return ids[0], nil
 }
 
-func (r *userRepo) GetByUsername(username string) (model.User, error) {
+// GetCachedByID returns the user from Redis, falling back to Postgres on a miss.
+// PasswordHash is never cached, so callers that verify passwords must use GetByID.
+func (r *userRepo) GetCachedByID(ctx context.Context, id string) (model.User, error) {
 	var user model.User
-	return user, r.db.Where("username = ?", username).Take(&user).Error
+	if err := r.rd.Get(ctx, userCacheKey(id), &user); err == nil {
+		return user, nil
+	}
+
+	user, err := r.GetByID(id)
+	if err != nil {
+		return user, err
+	}
+
+	if err := r.rd.Set(ctx, userCacheKey(id), user, userCacheTTL); err != nil {
+		r.logger.Warn("user cache set failed", logger.String("user_id", id), logger.Error(err))
+	}
+
+	return user, nil
+}
+
+func (r *userRepo) DeleteCache(ctx context.Context, id string) error {
+	return r.rd.Delete(ctx, userCacheKey(id))
+}
+
+func userCacheKey(id string) string {
+	return "user:cache:" + id
 }
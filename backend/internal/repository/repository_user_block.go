@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/asliddinberdiev/eirsystem/config"
+	"github.com/asliddinberdiev/eirsystem/internal/model"
+	"github.com/asliddinberdiev/eirsystem/pkg/logger"
+	"github.com/asliddinberdiev/eirsystem/pkg/postgres"
+	"gorm.io/gorm"
+)
+
+type UserBlock interface {
+	Create(block *model.UserBlock) error
+	GetActiveByUserID(userID string) (model.UserBlock, error)
+	GetAllByUserID(userID string) ([]model.UserBlock, error)
+	Release(userID string, unblockedBy *string) error
+}
+
+type userBlockRepo struct {
+	cfg    *config.Config
+	logger logger.Logger
+	db     *gorm.DB
+}
+
+func NewUserBlockRepository(cfg *config.Config, logger logger.Logger, db *gorm.DB) UserBlock {
+	return &userBlockRepo{cfg: cfg, logger: logger, db: db}
+}
+
+// Create stores the block and deactivates the user in one transaction.
+func (r *userBlockRepo) Create(block *model.UserBlock) error {
+	return tenantScope(r.db, block.TenantID).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(block).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.User{}).Where("id = ?", block.UserID).Update("is_active", false).Error
+	})
+}
+
+func (r *userBlockRepo) GetActiveByUserID(userID string) (model.UserBlock, error) {
+	var block model.UserBlock
+	return block, postgres.Bypass(r.db).
+		Where("user_id = ? AND unblocked_at IS NULL", userID).
+		Order("created_at DESC").
+		Take(&block).Error
+}
+
+func (r *userBlockRepo) GetAllByUserID(userID string) ([]model.UserBlock, error) {
+	var blocks []model.UserBlock
+	return blocks, postgres.Bypass(r.db).Where("user_id = ?", userID).Order("created_at DESC").Find(&blocks).Error
+}
+
+// Release closes every open block of the user and reactivates the account.
+// A nil unblockedBy marks a block that ended by expiring.
+func (r *userBlockRepo) Release(userID string, unblockedBy *string) error {
+	return postgres.Bypass(r.db).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.UserBlock{}).
+			Where("user_id = ? AND unblocked_at IS NULL", userID).
+			Updates(map[string]any{"unblocked_by": unblockedBy, "unblocked_at": time.Now()}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&model.User{}).Where("id = ?", userID).Update("is_active", true).Error
+	})
+}
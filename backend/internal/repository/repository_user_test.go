@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/asliddinberdiev/eirsystem/config"
+	"github.com/asliddinberdiev/eirsystem/internal/model"
+	"github.com/asliddinberdiev/eirsystem/pkg/logger"
+	"github.com/asliddinberdiev/eirsystem/pkg/postgres"
+	"github.com/asliddinberdiev/eirsystem/pkg/postgres/postgrestest"
+	"github.com/google/uuid"
+)
+
+func TestUserGetAllOnlyReturnsTenantUsers(t *testing.T) {
+	db := postgrestest.Open(t)
+	tenantA := postgrestest.CreateTenant(t, db)
+	tenantB := postgrestest.CreateTenant(t, db)
+
+	var want []string
+	for _, tenantID := range []string{tenantA, tenantA, tenantB} {
+		id := uuid.New().String()
+		user := model.User{
+			ID:           id,
+			TenantID:     tenantID,
+			FullName:     "Test User",
+			Username:     "user-" + id[:8],
+			PasswordHash: "x",
+			Role:         "doctor",
+			IsActive:     true,
+		}
+		if err := postgres.Tenant(db, tenantID).Create(&user).Error; err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+		if tenantID == tenantA {
+			want = append(want, id)
+		}
+	}
+
+	log, err := logger.New(config.Logger{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := NewUserRepository(&config.Config{}, log, db, nil)
+
+	users, err := repo.GetAll(tenantA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != len(want) {
+		t.Fatalf("GetAll returned %d users, want %d", len(users), len(want))
+	}
+	for _, user := range users {
+		if user.TenantID != tenantA {
+			t.Errorf("GetAll returned user %s of tenant %s", user.ID, user.TenantID)
+		}
+	}
+}
@@ -10,13 +10,33 @@ import (
 )
 
 type Service struct {
-	User   User
-	Policy Policy
+	Tenant         Tenant
+	User           User
+	UserBlock      UserBlock
+	TwoFactor      TwoFactor
+	SecurityEvent  SecurityEvent
+	LoginAttempt   LoginAttempt
+	Password       Password
+	ServiceAccount ServiceAccount
+	Impersonation  Impersonation
+	OIDC           OIDC
+	Policy         Policy
 }
 
-func New(cfg *config.Config, logger logger.Logger, s3 *minio.Client, repo *repository.Repository, enforcer *casbin.Enforcer) *Service {
+func New(cfg *config.Config, logger logger.Logger, s3 *minio.Client, repo *repository.Repository, enforcer *casbin.SyncedEnforcer) *Service {
+	policy := NewPolicyService(repo, enforcer)
+
 	return &Service{
-		User:   NewUserService(cfg, logger, s3, repo),
-		Policy: NewPolicyService(enforcer),
+		Tenant:         NewTenantService(cfg, logger, repo, policy),
+		User:           NewUserService(cfg, logger, s3, repo),
+		UserBlock:      NewUserBlockService(cfg, logger, repo),
+		TwoFactor:      NewTwoFactorService(cfg, logger, repo),
+		SecurityEvent:  NewSecurityEventService(cfg, logger, repo),
+		LoginAttempt:   NewLoginAttemptService(cfg, logger, repo),
+		Password:       NewPasswordService(cfg, logger, repo),
+		ServiceAccount: NewServiceAccountService(cfg, logger, repo),
+		Impersonation:  NewImpersonationService(cfg, logger, repo),
+		OIDC:           NewOIDCService(cfg, logger, repo),
+		Policy:         policy,
 	}
 }
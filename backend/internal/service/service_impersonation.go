@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"html"
+
+	"github.com/asliddinberdiev/eirsystem/config"
+	"github.com/asliddinberdiev/eirsystem/internal/model"
+	"github.com/asliddinberdiev/eirsystem/internal/repository"
+	"github.com/asliddinberdiev/eirsystem/pkg/logger"
+	"github.com/asliddinberdiev/eirsystem/pkg/telegram"
+	"github.com/google/uuid"
+)
+
+type Impersonation interface {
+	Start(ctx context.Context, impersonation *model.Impersonation) error
+	LogRequest(ctx context.Context, request model.ImpersonationRequest)
+	SetConsent(ctx context.Context, userID string, allow bool) error
+}
+
+type impersonationServ struct {
+	cfg    *config.Config
+	logger logger.Logger
+	repo   *repository.Repository
+}
+
+func NewImpersonationService(cfg *config.Config, logger logger.Logger, repo *repository.Repository) Impersonation {
+	return &impersonationServ{
+		cfg:    cfg,
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+// Start records who impersonates whom and why, and alerts the operators.
+// Unlike LogRequest it fails the caller, so no token is handed out unaudited.
+func (s *impersonationServ) Start(ctx context.Context, impersonation *model.Impersonation) error {
+	impersonation.ID = uuid.New().String()
+
+	if err := s.repo.Impersonation.Create(impersonation); err != nil {
+		return err
+	}
+
+	s.logger.Warn("Impersonation started",
+		logger.String("user_id", impersonation.UserID),
+		logger.String("impersonator_id", impersonation.ImpersonatorID),
+		logger.String("session_id", impersonation.SessionID),
+	)
+
+	telegram.Send(fmt.Sprintf(
+		"🎭 <b>IMPERSONATION</b>\n\n"+
+			"🕵️ <b>By:</b> <code>%s</code>\n"+
+			"👤 <b>As:</b> <code>%s</code>\n"+
+			"🔑 <b>Session:</b> <code>%s</code>\n"+
+			"📝 <b>Reason:</b> %s",
+		impersonation.ImpersonatorID, impersonation.UserID, impersonation.SessionID,
+		html.EscapeString(impersonation.Reason),
+	))
+
+	return nil
+}
+
+// LogRequest records a request made under impersonation with both identities.
+// It never fails the caller; the log line is kept even if the row is lost.
+func (s *impersonationServ) LogRequest(ctx context.Context, request model.ImpersonationRequest) {
+	request.ID = uuid.New().String()
+
+	s.logger.Info("Impersonated request",
+		logger.String("user_id", request.UserID),
+		logger.String("impersonator_id", request.ImpersonatorID),
+		logger.String("session_id", request.SessionID),
+		logger.String("method", request.Method),
+		logger.String("path", request.Path),
+		logger.Int("status", request.Status),
+		logger.String("request_id", request.RequestID),
+	)
+
+	if err := s.repo.Impersonation.CreateRequest(&request); err != nil {
+		s.logger.Error("impersonation request save failed", logger.Any("request", request), logger.Error(err))
+	}
+}
+
+func (s *impersonationServ) SetConsent(ctx context.Context, userID string, allow bool) error {
+	if err := s.repo.Impersonation.SetConsent(userID, allow); err != nil {
+		return err
+	}
+	return s.repo.User.DeleteCache(ctx, userID)
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/asliddinberdiev/eirsystem/config"
+	"github.com/asliddinberdiev/eirsystem/internal/repository"
+	"github.com/asliddinberdiev/eirsystem/pkg/logger"
+)
+
+const (
+	lockoutScopeUser = "user"
+	lockoutScopeIP   = "ip"
+
+	defaultUserMaxAttempts = 5
+	defaultIPMaxAttempts   = 20
+	defaultLockoutWindow   = time.Hour
+	defaultLockoutBase     = 30 * time.Second
+	defaultLockoutMax      = time.Hour
+)
+
+type LoginAttempt interface {
+	Check(ctx context.Context, tenantID, username, clientIP string) (time.Duration, error)
+	Fail(ctx context.Context, tenantID, username, clientIP string) (time.Duration, error)
+	Reset(ctx context.Context, tenantID, username string) error
+	ResetIP(ctx context.Context, clientIP string) error
+}
+
+type loginAttemptServ struct {
+	cfg    *config.Config
+	logger logger.Logger
+	repo   *repository.Repository
+}
+
+func NewLoginAttemptService(cfg *config.Config, logger logger.Logger, repo *repository.Repository) LoginAttempt {
+	return &loginAttemptServ{
+		cfg:    cfg,
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+// Check returns how long sign-in stays locked for the username or the client IP,
+// whichever is longer, or zero when neither is locked.
+func (s *loginAttemptServ) Check(ctx context.Context, tenantID, username, clientIP string) (time.Duration, error) {
+	userLock, err := s.repo.LoginAttempt.GetLockout(ctx, lockoutScopeUser, accountSubject(tenantID, username))
+	if err != nil {
+		return 0, err
+	}
+
+	ipLock, err := s.repo.LoginAttempt.GetLockout(ctx, lockoutScopeIP, clientIP)
+	if err != nil {
+		return 0, err
+	}
+
+	return max(userLock, ipLock), nil
+}
+
+// Fail records a failed sign-in and locks the username or IP once its failures
+// reach the limit. Every further failure doubles the lockout up to the maximum.
+// It returns the lockout that started, or zero when sign-in may be retried at once.
+func (s *loginAttemptServ) Fail(ctx context.Context, tenantID, username, clientIP string) (time.Duration, error) {
+	cfg := s.cfg.Lockout
+
+	userLock, err := s.fail(ctx, lockoutScopeUser, accountSubject(tenantID, username), withDefault(cfg.UserMaxAttempts, defaultUserMaxAttempts))
+	if err != nil {
+		return 0, err
+	}
+
+	ipLock, err := s.fail(ctx, lockoutScopeIP, clientIP, withDefault(cfg.IPMaxAttempts, defaultIPMaxAttempts))
+	if err != nil {
+		return 0, err
+	}
+
+	if userLock > 0 {
+		s.logger.Warn("Sign-in locked for username",
+			logger.String("tenant_id", tenantID),
+			logger.String("username", username),
+			logger.String("client_ip", clientIP),
+			logger.Duration("lockout", userLock),
+		)
+	}
+	if ipLock > 0 {
+		s.logger.Warn("Sign-in locked for IP",
+			logger.String("client_ip", clientIP),
+			logger.Duration("lockout", ipLock),
+		)
+	}
+
+	return max(userLock, ipLock), nil
+}
+
+// Reset lifts the lockout of the username and forgets its failures. Failures
+// counted against the IP are kept, so a valid account cannot be used to reset
+// a guessing run from the same address.
+func (s *loginAttemptServ) Reset(ctx context.Context, tenantID, username string) error {
+	return s.repo.LoginAttempt.Reset(ctx, lockoutScopeUser, accountSubject(tenantID, username))
+}
+
+// ResetIP lifts the lockout of a client IP and forgets its failures. The IP is
+// shared by every tenant, so only the system role may reset it.
+func (s *loginAttemptServ) ResetIP(ctx context.Context, clientIP string) error {
+	return s.repo.LoginAttempt.Reset(ctx, lockoutScopeIP, clientIP)
+}
+
+func (s *loginAttemptServ) fail(ctx context.Context, scope, subject string, limit int) (time.Duration, error) {
+	cfg := s.cfg.Lockout
+
+	failures, err := s.repo.LoginAttempt.AddFailure(ctx, scope, subject, withDefault(cfg.Window, defaultLockoutWindow))
+	if err != nil {
+		return 0, err
+	}
+	if failures < int64(limit) {
+		return 0, nil
+	}
+
+	lockout := backoff(failures-int64(limit), withDefault(cfg.BaseDelay, defaultLockoutBase), withDefault(cfg.MaxDelay, defaultLockoutMax))
+	if err := s.repo.LoginAttempt.Lock(ctx, scope, subject, lockout); err != nil {
+		return 0, err
+	}
+	return lockout, nil
+}
+
+func backoff(step int64, base, limit time.Duration) time.Duration {
+	delay := base
+	for range step {
+		if delay >= limit/2 {
+			return limit
+		}
+		delay *= 2
+	}
+	return min(delay, limit)
+}
+
+// accountSubject keys the failures of a username within its tenant; system
+// accounts sign in without a tenant and share the empty one.
+func accountSubject(tenantID, username string) string {
+	return tenantID + ":" + strings.ToLower(strings.TrimSpace(username))
+}
+
+func withDefault[T comparable](value, fallback T) T {
+	var zero T
+	if value == zero {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,275 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/asliddinberdiev/eirsystem/config"
+	"github.com/asliddinberdiev/eirsystem/internal/model"
+	"github.com/asliddinberdiev/eirsystem/internal/repository"
+	"github.com/asliddinberdiev/eirsystem/pkg/logger"
+	"github.com/asliddinberdiev/eirsystem/pkg/oidc"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	oidcStateTTL = 10 * time.Minute
+
+	// ssoPasswordHash never matches a password, so provisioned users can
+	// only sign in through their identity provider.
+	ssoPasswordHash = "!sso"
+
+	maxUsernameLength = 50
+	maxFullNameLength = 100
+)
+
+var (
+	ErrOIDCNotConfigured  = errors.New("single sign-on is not configured for this tenant")
+	ErrOIDCStateInvalid   = errors.New("sign-in request is unknown or has expired")
+	ErrOIDCUserNotFound   = errors.New("no user is linked to this identity and provisioning is disabled")
+	ErrOIDCRoleUnmapped   = errors.New("none of the identity's groups is mapped to a role")
+	ErrOIDCUsernameExists = errors.New("a user with this username already exists")
+)
+
+// OIDCLogin is the user signed in through SSO. Provisioned tells the caller to
+// grant the user's role in the enforcer. RoleChanged means the mapped groups
+// give a role other than PreviousRole; the caller stores it with SaveRole
+// once the user is known to be active.
+type OIDCLogin struct {
+	User         model.User
+	PreviousRole string
+	Provisioned  bool
+	RoleChanged  bool
+}
+
+type OIDC interface {
+	GetProvider(tenantID string) (model.TenantOIDCProvider, error)
+	SetProvider(ctx context.Context, provider *model.TenantOIDCProvider) error
+	DeleteProvider(tenantID string) error
+
+	Begin(ctx context.Context, tenantID string) (string, error)
+	Complete(ctx context.Context, state, code string) (OIDCLogin, error)
+	SaveRole(ctx context.Context, login OIDCLogin) error
+}
+
+type oidcServ struct {
+	cfg    *config.Config
+	logger logger.Logger
+	repo   *repository.Repository
+	client *oidc.Client
+}
+
+func NewOIDCService(cfg *config.Config, logger logger.Logger, repo *repository.Repository) OIDC {
+	return &oidcServ{
+		cfg:    cfg,
+		logger: logger,
+		repo:   repo,
+		client: oidc.NewClient(),
+	}
+}
+
+func (s *oidcServ) GetProvider(tenantID string) (model.TenantOIDCProvider, error) {
+	return s.repo.OIDC.GetProvider(tenantID)
+}
+
+// SetProvider saves the tenant's provider once its discovery document could
+// be fetched, so a mistyped issuer is reported now rather than at sign-in.
+func (s *oidcServ) SetProvider(ctx context.Context, provider *model.TenantOIDCProvider) error {
+	provider.Issuer = strings.TrimSuffix(provider.Issuer, "/")
+
+	if _, err := s.client.Discover(ctx, provider.Issuer); err != nil {
+		return err
+	}
+
+	return s.repo.OIDC.SetProvider(provider)
+}
+
+func (s *oidcServ) DeleteProvider(tenantID string) error {
+	return s.repo.OIDC.DeleteProvider(tenantID)
+}
+
+// Begin starts the authorization code flow and returns the URL of the
+// provider's sign-in page. The PKCE verifier and nonce stay on the server.
+func (s *oidcServ) Begin(ctx context.Context, tenantID string) (string, error) {
+	provider, err := s.enabledProvider(tenantID)
+	if err != nil {
+		return "", err
+	}
+
+	meta, err := s.client.Discover(ctx, provider.Issuer)
+	if err != nil {
+		return "", err
+	}
+
+	state, err := oidc.RandomString()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		return "", err
+	}
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		return "", err
+	}
+
+	err = s.repo.OIDC.SaveState(ctx, state, repository.OIDCState{
+		TenantID:     tenantID,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+	}, oidcStateTTL)
+	if err != nil {
+		return "", err
+	}
+
+	return oidc.AuthCodeURL(meta, provider.ClientID, provider.RedirectURL, provider.Scopes, state, nonce, challenge), nil
+}
+
+// Complete redeems the code returned to the callback and resolves the user
+// linked to the identity, provisioning one when the tenant allows it. Mapped
+// groups also give an existing user a new role, which SaveRole stores.
+func (s *oidcServ) Complete(ctx context.Context, state, code string) (OIDCLogin, error) {
+	pending, ok, err := s.repo.OIDC.TakeState(ctx, state)
+	if err != nil {
+		return OIDCLogin{}, err
+	}
+	if !ok {
+		return OIDCLogin{}, ErrOIDCStateInvalid
+	}
+
+	provider, err := s.enabledProvider(pending.TenantID)
+	if err != nil {
+		return OIDCLogin{}, err
+	}
+
+	meta, err := s.client.Discover(ctx, provider.Issuer)
+	if err != nil {
+		return OIDCLogin{}, err
+	}
+
+	tokens, err := s.client.Exchange(ctx, meta, provider.ClientID, provider.ClientSecret, provider.RedirectURL, code, pending.CodeVerifier)
+	if err != nil {
+		return OIDCLogin{}, err
+	}
+
+	claims, err := s.client.VerifyIDToken(ctx, meta, provider.ClientID, pending.Nonce, tokens.IDToken)
+	if err != nil {
+		return OIDCLogin{}, err
+	}
+
+	subject := claims.String("sub")
+	role := provider.MapRole(claims.Strings(provider.GroupsClaim))
+
+	identity, err := s.repo.OIDC.GetIdentity(provider.TenantID, provider.Issuer, subject)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return s.provision(provider, claims, subject, role)
+	}
+	if err != nil {
+		return OIDCLogin{}, err
+	}
+
+	user, err := s.repo.User.GetByID(identity.UserID)
+	if err != nil {
+		return OIDCLogin{}, err
+	}
+
+	login := OIDCLogin{User: user, PreviousRole: user.Role}
+	if role != "" && role != user.Role && user.Role != "owner" && user.Role != "system" {
+		login.User.Role = role
+		login.RoleChanged = true
+	}
+
+	return login, nil
+}
+
+// SaveRole stores the role the identity's groups map to on the user.
+func (s *oidcServ) SaveRole(ctx context.Context, login OIDCLogin) error {
+	user := login.User
+	if err := s.repo.OIDC.UpdateRole(user.ID, user.Role); err != nil {
+		return err
+	}
+	if err := s.repo.User.DeleteCache(ctx, user.ID); err != nil {
+		s.logger.Warn("user cache delete failed", logger.String("user_id", user.ID), logger.Error(err))
+	}
+
+	s.logger.Info("SSO role changed", logger.String("user_id", user.ID), logger.String("from", login.PreviousRole), logger.String("to", user.Role))
+	return nil
+}
+
+func (s *oidcServ) provision(provider model.TenantOIDCProvider, claims oidc.Claims, subject, role string) (OIDCLogin, error) {
+	if !provider.AutoProvision {
+		return OIDCLogin{}, ErrOIDCUserNotFound
+	}
+
+	if role == "" && provider.DefaultRole != nil {
+		role = *provider.DefaultRole
+	}
+	if role == "" {
+		return OIDCLogin{}, ErrOIDCRoleUnmapped
+	}
+
+	username := cmp.Or(claims.String(provider.UsernameClaim), claims.String("email"), subject)
+	username = truncate(strings.ToLower(username), maxUsernameLength)
+
+	_, err := s.repo.User.GetByUsername(provider.TenantID, username)
+	if err == nil {
+		// Never link an existing local account by name: anyone able to pick
+		// that name at the provider would take the account over.
+		return OIDCLogin{}, ErrOIDCUsernameExists
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return OIDCLogin{}, err
+	}
+
+	user := model.User{
+		ID:           uuid.New().String(),
+		TenantID:     provider.TenantID,
+		FullName:     truncate(cmp.Or(claims.String(provider.NameClaim), username), maxFullNameLength),
+		Username:     username,
+		PasswordHash: ssoPasswordHash,
+		Role:         role,
+		IsActive:     true,
+	}
+	identity := model.UserIdentity{
+		ID:       uuid.New().String(),
+		TenantID: provider.TenantID,
+		UserID:   user.ID,
+		Issuer:   provider.Issuer,
+		Subject:  subject,
+	}
+
+	if err := s.repo.OIDC.CreateUser(&user, &identity); err != nil {
+		return OIDCLogin{}, err
+	}
+
+	s.logger.Info("SSO user provisioned", logger.String("user_id", user.ID), logger.String("tenant_id", user.TenantID), logger.String("role", role))
+
+	return OIDCLogin{User: user, Provisioned: true}, nil
+}
+
+func (s *oidcServ) enabledProvider(tenantID string) (model.TenantOIDCProvider, error) {
+	provider, err := s.repo.OIDC.GetProvider(tenantID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return provider, ErrOIDCNotConfigured
+	}
+	if err != nil {
+		return provider, err
+	}
+	if !provider.IsEnabled {
+		return provider, ErrOIDCNotConfigured
+	}
+	return provider, nil
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
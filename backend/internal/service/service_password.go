@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/asliddinberdiev/eirsystem/config"
+	"github.com/asliddinberdiev/eirsystem/internal/model"
+	"github.com/asliddinberdiev/eirsystem/internal/repository"
+	"github.com/asliddinberdiev/eirsystem/pkg/hasher"
+	"github.com/asliddinberdiev/eirsystem/pkg/logger"
+	"github.com/asliddinberdiev/eirsystem/pkg/validator"
+	"gorm.io/gorm"
+)
+
+const (
+	TemporaryPasswordTTL = 24 * time.Hour
+
+	temporaryPasswordSize    = 16
+	defaultPasswordMinLength = 8
+)
+
+// temporaryPasswordClasses holds one alphabet per character class, without
+// look-alike characters, so a temporary password satisfies any tenant policy.
+var temporaryPasswordClasses = []string{
+	"ABCDEFGHJKLMNPQRSTUVWXYZ",
+	"abcdefghijkmnpqrstuvwxyz",
+	"23456789",
+	"!@#$%*-_+=?",
+}
+
+var (
+	ErrPasswordWrong  = errors.New("current password is incorrect")
+	ErrPasswordReused = errors.New("password was used recently, choose another one")
+)
+
+type TemporaryPassword struct {
+	Password  string
+	ExpiresAt time.Time
+}
+
+type Password interface {
+	Change(ctx context.Context, userID, currentPassword, newPassword string) error
+	Reset(ctx context.Context, userID string) (TemporaryPassword, error)
+	Rehash(ctx context.Context, user model.User, password string) error
+
+	GetPolicy(tenantID string) (model.TenantPasswordPolicy, error)
+	SetPolicy(policy *model.TenantPasswordPolicy) error
+}
+
+type passwordServ struct {
+	cfg    *config.Config
+	logger logger.Logger
+	repo   *repository.Repository
+}
+
+func NewPasswordService(cfg *config.Config, logger logger.Logger, repo *repository.Repository) Password {
+	return &passwordServ{
+		cfg:    cfg,
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+// Change sets a new password after checking the current one, the tenant
+// policy and the password history. It also clears a pending forced change.
+func (s *passwordServ) Change(ctx context.Context, userID, currentPassword, newPassword string) error {
+	user, err := s.repo.User.GetByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := hasher.Verify(currentPassword, user.PasswordHash); err != nil {
+		return ErrPasswordWrong
+	}
+
+	policy, err := s.GetPolicy(user.TenantID)
+	if err != nil {
+		return err
+	}
+
+	err = validator.Password(newPassword, validator.PasswordPolicy{
+		MinLength:     policy.MinLength,
+		RequireUpper:  policy.RequireUpper,
+		RequireLower:  policy.RequireLower,
+		RequireDigit:  policy.RequireDigit,
+		RequireSymbol: policy.RequireSymbol,
+	})
+	if err != nil {
+		return err
+	}
+
+	previous := []string{user.PasswordHash}
+	if policy.HistorySize > 0 {
+		history, err := s.repo.Password.GetHistory(userID, policy.HistorySize)
+		if err != nil {
+			return err
+		}
+		previous = append(previous, history...)
+	}
+	for _, passwordHash := range previous {
+		if hasher.Verify(newPassword, passwordHash) == nil {
+			return ErrPasswordReused
+		}
+	}
+
+	passwordHash, err := hasher.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.Password.Update(userID, passwordHash, nil); err != nil {
+		return err
+	}
+	return s.repo.User.DeleteCache(ctx, userID)
+}
+
+// Rehash upgrades the stored hash of a password that was just verified when it
+// was made with an older algorithm or weaker settings than new hashes are.
+func (s *passwordServ) Rehash(ctx context.Context, user model.User, password string) error {
+	if !hasher.NeedsRehash(user.PasswordHash) {
+		return nil
+	}
+
+	passwordHash, err := hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.Password.SetHash(user.ID, passwordHash); err != nil {
+		return err
+	}
+	return s.repo.User.DeleteCache(ctx, user.ID)
+}
+
+// Reset replaces the password with a random temporary one that only lets the
+// user sign in to choose a new password before it expires.
+func (s *passwordServ) Reset(ctx context.Context, userID string) (TemporaryPassword, error) {
+	password, err := generateTemporaryPassword()
+	if err != nil {
+		return TemporaryPassword{}, err
+	}
+
+	passwordHash, err := hasher.Hash(password)
+	if err != nil {
+		return TemporaryPassword{}, err
+	}
+
+	expiresAt := time.Now().Add(TemporaryPasswordTTL)
+	if err := s.repo.Password.Update(userID, passwordHash, &expiresAt); err != nil {
+		return TemporaryPassword{}, err
+	}
+
+	return TemporaryPassword{Password: password, ExpiresAt: expiresAt}, s.repo.User.DeleteCache(ctx, userID)
+}
+
+// GetPolicy returns the password policy of the tenant, or the default policy
+// when the tenant has not configured one or the user has no tenant.
+func (s *passwordServ) GetPolicy(tenantID string) (model.TenantPasswordPolicy, error) {
+	if tenantID == "" {
+		return defaultPasswordPolicy(tenantID), nil
+	}
+
+	policy, err := s.repo.Password.GetPolicy(tenantID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return defaultPasswordPolicy(tenantID), nil
+	}
+	return policy, err
+}
+
+func (s *passwordServ) SetPolicy(policy *model.TenantPasswordPolicy) error {
+	return s.repo.Password.SetPolicy(policy)
+}
+
+func defaultPasswordPolicy(tenantID string) model.TenantPasswordPolicy {
+	return model.TenantPasswordPolicy{
+		TenantID:  tenantID,
+		MinLength: defaultPasswordMinLength,
+	}
+}
+
+func generateTemporaryPassword() (string, error) {
+	password := make([]byte, temporaryPasswordSize)
+	for i := range password {
+		class := temporaryPasswordClasses[i%len(temporaryPasswordClasses)]
+		c, err := randomIndex(len(class))
+		if err != nil {
+			return "", err
+		}
+		password[i] = class[c]
+	}
+
+	for i := len(password) - 1; i > 0; i-- {
+		j, err := randomIndex(i + 1)
+		if err != nil {
+			return "", err
+		}
+		password[i], password[j] = password[j], password[i]
+	}
+
+	return string(password), nil
+}
+
+func randomIndex(n int) (int, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(i.Int64()), nil
+}
@@ -1,18 +1,88 @@
 package service
 
-import "github.com/casbin/casbin/v3"
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/asliddinberdiev/eirsystem/internal/model"
+	"github.com/asliddinberdiev/eirsystem/internal/repository"
+	"github.com/asliddinberdiev/eirsystem/pkg/permission"
+	"github.com/casbin/casbin/v3"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrRoleNotFound  = errors.New("role not found")
+	ErrRoleExists    = errors.New("role already exists")
+	ErrRoleProtected = errors.New("role cannot be changed")
+
+	ErrBranchNotFound = errors.New("branch not found")
+)
+
+type Role struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	BuiltIn     bool     `json:"built_in"`
+	Permissions []string `json:"permissions"`
+}
+
+// UserRole is a role held by a user, either tenant-wide or, when BranchID is
+// set, in that branch only.
+type UserRole struct {
+	Role     string `json:"role"`
+	BranchID string `json:"branch_id,omitempty"`
+}
+
+// EffectivePermissions is what a user may do in its tenant: the permissions
+// its tenant-wide roles grant, and for each branch it can work in every
+// permission it holds there.
+type EffectivePermissions struct {
+	Roles       []UserRole          `json:"roles"`
+	Permissions []string            `json:"permissions"`
+	Branches    []BranchPermissions `json:"branches"`
+}
+
+type BranchPermissions struct {
+	model.Branch
+	Permissions []string `json:"permissions"`
+}
 
 type Policy interface {
 	AddRoleToUser(userID string, roleName string, clinicID string) error
+	RemoveUser(userID string, clinicID string) error
+	ReplaceUserRole(userID, from, to, tenantID string) error
 	SetupDefaultPolicies(clinicID string) error
+	DefaultPolicies(tenantID, ownerID string) model.TenantPolicies
+	LoadPolicies(policies model.TenantPolicies) error
+	UpgradePolicyTemplates() error
+
+	GetRoles(tenantID string) ([]Role, error)
+	CreateRole(role *model.TenantRole) error
+	DeleteRole(tenantID, name string) error
+	GrantPermission(tenantID, role, permission string) error
+	RevokePermission(tenantID, role, permission string) error
+
+	GetUserRoles(userID, tenantID string) ([]UserRole, error)
+	AssignRole(userID, role, tenantID, branchID string) error
+	UnassignRole(userID, role, tenantID, branchID string) error
+	GetUserBranches(userID, tenantID string) ([]model.Branch, error)
+	GetSubject(userID, tenantID string, branches model.BranchScope) (model.Subject, error)
+	GetEffectivePermissions(userID, tenantID string, names []string) (EffectivePermissions, error)
+	Explain(userID, tenantID, branchID, permission string) (Explanation, error)
+	DryRun(tenantID string, changes []PolicyChange, permissions []string) ([]AccessChange, error)
 }
 
 type policyService struct {
-	enforcer *casbin.Enforcer
+	repo     *repository.Repository
+	enforcer *casbin.SyncedEnforcer
 }
 
-func NewPolicyService(enforcer *casbin.Enforcer) Policy {
+func NewPolicyService(repo *repository.Repository, enforcer *casbin.SyncedEnforcer) Policy {
 	return &policyService{
+		repo:     repo,
 		enforcer: enforcer,
 	}
 }
@@ -22,44 +92,430 @@ func (s *policyService) AddRoleToUser(userID string, roleName string, clinicID s
 	return err
 }
 
+func (s *policyService) RemoveUser(userID string, clinicID string) error {
+	_, err := s.enforcer.DeleteRolesForUser(userID, clinicID)
+	return err
+}
+
+// ReplaceUserRole moves the user from one tenant-wide role to another. Other
+// roles assigned to the user are kept.
+func (s *policyService) ReplaceUserRole(userID, from, to, tenantID string) error {
+	if _, err := s.enforcer.DeleteRoleForUserInDomain(userID, from, tenantID); err != nil {
+		return err
+	}
+	_, err := s.enforcer.AddRoleForUserInDomain(userID, to, tenantID)
+	return err
+}
+
+// SetupDefaultPolicies grants every role the permissions of its template in
+// the tenant's domain.
 func (s *policyService) SetupDefaultPolicies(clinicID string) error {
-	// Clinic Owner Permissions
-	if _, err := s.enforcer.AddPolicy("role:owner", clinicID, "/api/v1/*", "GET"); err != nil {
+	return s.applyTemplates(clinicID)
+}
+
+// DefaultPolicies builds the policies of a new tenant: every role template in
+// full, and the owner role for its owner. They are stored together with the
+// tenant, then loaded with LoadPolicies.
+func (s *policyService) DefaultPolicies(tenantID, ownerID string) model.TenantPolicies {
+	policies := model.TenantPolicies{
+		Groupings: [][]string{{ownerID, "owner", tenantID}},
+	}
+
+	for _, role := range slices.Sorted(maps.Keys(permission.Templates)) {
+		template := permission.Templates[role]
+		for _, name := range template.Permissions {
+			obj, act := permission.Split(name)
+			policies.Policies = append(policies.Policies, []string{role, tenantID, obj, act})
+		}
+		policies.Templates = append(policies.Templates, model.TenantPolicyTemplate{
+			TenantID:    tenantID,
+			Role:        role,
+			Version:     template.Version,
+			Permissions: template.Permissions,
+		})
+	}
+
+	return policies
+}
+
+// LoadPolicies adds policies that are already stored to the enforcer and
+// tells the other instances. Writing them to the adapter again is a no-op.
+func (s *policyService) LoadPolicies(policies model.TenantPolicies) error {
+	if len(policies.Policies) > 0 {
+		if _, err := s.enforcer.AddPoliciesEx(policies.Policies); err != nil {
+			return err
+		}
+	}
+	if len(policies.Groupings) > 0 {
+		if _, err := s.enforcer.AddGroupingPoliciesEx(policies.Groupings); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpgradePolicyTemplates brings every tenant up to the current template
+// versions. It is safe to run on every start and on several instances at once.
+func (s *policyService) UpgradePolicyTemplates() error {
+	tenantIDs, err := s.repo.Tenant.GetAllIDs()
+	if err != nil {
+		return err
+	}
+
+	for _, tenantID := range tenantIDs {
+		if err := s.applyTemplates(tenantID); err != nil {
+			return fmt.Errorf("tenant %s: %w", tenantID, err)
+		}
+	}
+	return nil
+}
+
+// applyTemplates applies the templates newer than the version the tenant has.
+// Only the difference between the applied and the current template is granted
+// or revoked, so permissions the tenant granted or revoked itself stay as they
+// are unless the template changes them too.
+func (s *policyService) applyTemplates(tenantID string) error {
+	records, err := s.repo.PolicyTemplate.GetAll(tenantID)
+	if err != nil {
+		return err
+	}
+
+	applied := make(map[string]model.TenantPolicyTemplate, len(records))
+	for _, record := range records {
+		applied[record.Role] = record
+	}
+
+	for role, template := range permission.Templates {
+		previous := applied[role]
+		if previous.Version >= template.Version {
+			continue
+		}
+
+		var granted [][]string
+		for _, name := range template.Permissions {
+			if !slices.Contains(previous.Permissions, name) {
+				obj, act := permission.Split(name)
+				granted = append(granted, []string{role, tenantID, obj, act})
+			}
+		}
+		if len(granted) > 0 {
+			if _, err := s.enforcer.AddPoliciesEx(granted); err != nil {
+				return err
+			}
+		}
+
+		for _, name := range previous.Permissions {
+			if !slices.Contains(template.Permissions, name) {
+				obj, act := permission.Split(name)
+				if _, err := s.enforcer.RemovePolicy(role, tenantID, obj, act); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := s.repo.PolicyTemplate.Save(&model.TenantPolicyTemplate{
+			TenantID:    tenantID,
+			Role:        role,
+			Version:     template.Version,
+			Permissions: template.Permissions,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetRoles lists the built-in roles and the tenant's custom roles with the
+// permissions granted to each in the tenant's domain.
+func (s *policyService) GetRoles(tenantID string) ([]Role, error) {
+	custom, err := s.repo.Role.GetAll(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := make([]Role, 0, len(model.BuiltInRoles)+len(custom))
+	for _, name := range model.BuiltInRoles {
+		roles = append(roles, Role{Name: name, BuiltIn: true})
+	}
+	for _, role := range custom {
+		roles = append(roles, Role{Name: role.Name, Description: role.Description})
+	}
+
+	for i := range roles {
+		roles[i].Permissions, err = s.permissions(roles[i].Name, tenantID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return roles, nil
+}
+
+func (s *policyService) CreateRole(role *model.TenantRole) error {
+	if slices.Contains(model.BuiltInRoles, role.Name) || role.Name == "system" {
+		return ErrRoleExists
+	}
+
+	exists, err := s.repo.Role.Exists(role.TenantID, role.Name)
+	if err != nil {
 		return err
 	}
-	if _, err := s.enforcer.AddPolicy("role:owner", clinicID, "/api/v1/*", "POST"); err != nil {
+	if exists {
+		return ErrRoleExists
+	}
+
+	role.ID = uuid.New().String()
+	return s.repo.Role.Create(role)
+}
+
+// DeleteRole removes a custom role with its permissions and assignments.
+func (s *policyService) DeleteRole(tenantID, name string) error {
+	if err := s.checkRole(tenantID, name); err != nil {
+		return err
+	}
+	if slices.Contains(model.BuiltInRoles, name) {
+		return ErrRoleProtected
+	}
+
+	if _, err := s.enforcer.RemoveFilteredPolicy(0, name, tenantID); err != nil {
 		return err
 	}
-	if _, err := s.enforcer.AddPolicy("role:owner", clinicID, "/api/v1/*", "DELETE"); err != nil {
+	if _, err := s.enforcer.RemoveFilteredGroupingPolicy(1, name, tenantID); err != nil {
 		return err
 	}
 
-	// Doctor Permissions
-	if _, err := s.enforcer.AddPolicy("role:doctor", clinicID, "/api/v1/patients", "GET"); err != nil {
+	rules, err := s.enforcer.GetFilteredGroupingPolicy(1, name)
+	if err != nil {
 		return err
 	}
-	if _, err := s.enforcer.AddPolicy("role:doctor", clinicID, "/api/v1/appointments", "POST"); err != nil {
+	for _, rule := range rules {
+		if _, ok := permission.BranchOf(tenantID, rule[2]); !ok {
+			continue
+		}
+		if _, err := s.enforcer.RemoveGroupingPolicy(rule[0], rule[1], rule[2]); err != nil {
+			return err
+		}
+	}
+
+	return s.repo.Role.Delete(tenantID, name)
+}
+
+func (s *policyService) GrantPermission(tenantID, role, name string) error {
+	if err := s.checkEditableRole(tenantID, role); err != nil {
 		return err
 	}
 
-	// Nurse Permissions
-	if _, err := s.enforcer.AddPolicy("role:nurse", clinicID, "/api/v1/patients", "GET"); err != nil {
+	obj, act := permission.Split(name)
+	_, err := s.enforcer.AddPolicy(role, tenantID, obj, act)
+	return err
+}
+
+func (s *policyService) RevokePermission(tenantID, role, name string) error {
+	if err := s.checkEditableRole(tenantID, role); err != nil {
 		return err
 	}
-	if _, err := s.enforcer.AddPolicy("role:nurse", clinicID, "/api/v1/vitals", "POST"); err != nil {
+
+	obj, act := permission.Split(name)
+	_, err := s.enforcer.RemovePolicy(role, tenantID, obj, act)
+	return err
+}
+
+// GetUserRoles lists the user's tenant-wide roles followed by its branch roles.
+func (s *policyService) GetUserRoles(userID, tenantID string) ([]UserRole, error) {
+	rules, err := s.enforcer.GetFilteredGroupingPolicy(0, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := make([]UserRole, 0, len(rules))
+	var branchRoles []UserRole
+	for _, rule := range rules {
+		if rule[2] == tenantID {
+			roles = append(roles, UserRole{Role: rule[1]})
+			continue
+		}
+		if branchID, ok := permission.BranchOf(tenantID, rule[2]); ok {
+			branchRoles = append(branchRoles, UserRole{Role: rule[1], BranchID: branchID})
+		}
+	}
+
+	return append(roles, branchRoles...), nil
+}
+
+// AssignRole gives the user a role in the whole tenant, or in one branch when
+// branchID is set.
+func (s *policyService) AssignRole(userID, role, tenantID, branchID string) error {
+	if err := s.checkEditableRole(tenantID, role); err != nil {
 		return err
 	}
 
-	// Test Permissions
-	if _, err := s.enforcer.AddPolicy("role:owner", clinicID, "/api/v1/test/owner", "GET"); err != nil {
+	domain, err := s.domain(tenantID, branchID)
+	if err != nil {
 		return err
 	}
-	if _, err := s.enforcer.AddPolicy("role:doctor", clinicID, "/api/v1/test/doctor", "GET"); err != nil {
+
+	_, err = s.enforcer.AddRoleForUserInDomain(userID, role, domain)
+	return err
+}
+
+func (s *policyService) UnassignRole(userID, role, tenantID, branchID string) error {
+	if err := s.checkEditableRole(tenantID, role); err != nil {
 		return err
 	}
-	if _, err := s.enforcer.AddPolicy("role:nurse", clinicID, "/api/v1/test/nurse", "GET"); err != nil {
+
+	domain, err := s.domain(tenantID, branchID)
+	if err != nil {
 		return err
 	}
 
+	_, err = s.enforcer.DeleteRoleForUserInDomain(userID, role, domain)
+	return err
+}
+
+// GetUserBranches lists the branches the user can work in: every branch of the
+// tenant for tenant-wide roles, otherwise the branches it holds a role in.
+func (s *policyService) GetUserBranches(userID, tenantID string) ([]model.Branch, error) {
+	branches, err := s.repo.Branch.GetAll(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := s.GetUserRoles(userID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	var branchIDs []string
+	for _, role := range roles {
+		if role.BranchID == "" {
+			return branches, nil
+		}
+		branchIDs = append(branchIDs, role.BranchID)
+	}
+
+	return slices.DeleteFunc(branches, func(branch model.Branch) bool {
+		return !slices.Contains(branchIDs, branch.ID)
+	}), nil
+}
+
+// GetSubject describes the caller for the row-level access rules applied by
+// repository.Visible.
+func (s *policyService) GetSubject(userID, tenantID string, branches model.BranchScope) (model.Subject, error) {
+	roles, err := s.GetUserRoles(userID, tenantID)
+	if err != nil {
+		return model.Subject{}, err
+	}
+
+	staffID, err := s.repo.User.GetStaffID(tenantID, userID)
+	if err != nil {
+		return model.Subject{}, err
+	}
+
+	subject := model.Subject{UserID: userID, StaffID: staffID, TenantID: tenantID, Branches: branches}
+	for _, role := range roles {
+		if !slices.Contains(subject.Roles, role.Role) {
+			subject.Roles = append(subject.Roles, role.Role)
+		}
+	}
+	return subject, nil
+}
+
+// GetEffectivePermissions resolves which of the given permissions the user
+// holds, asking the enforcer the same way Authorizer does.
+func (s *policyService) GetEffectivePermissions(userID, tenantID string, names []string) (EffectivePermissions, error) {
+	roles, err := s.GetUserRoles(userID, tenantID)
+	if err != nil {
+		return EffectivePermissions{}, err
+	}
+
+	branches, err := s.GetUserBranches(userID, tenantID)
+	if err != nil {
+		return EffectivePermissions{}, err
+	}
+
+	effective := EffectivePermissions{Roles: roles, Branches: make([]BranchPermissions, 0, len(branches))}
+	effective.Permissions, err = s.granted(userID, tenantID, "", names)
+	if err != nil {
+		return EffectivePermissions{}, err
+	}
+
+	for _, branch := range branches {
+		permissions, err := s.granted(userID, tenantID, permission.BranchDomain(tenantID, branch.ID), names)
+		if err != nil {
+			return EffectivePermissions{}, err
+		}
+		effective.Branches = append(effective.Branches, BranchPermissions{Branch: branch, Permissions: permissions})
+	}
+
+	return effective, nil
+}
+
+// granted returns the names the user holds in the tenant, or in one of its
+// branches when branch is a branch domain.
+func (s *policyService) granted(userID, tenantID, branch string, names []string) ([]string, error) {
+	permissions := make([]string, 0, len(names))
+	for _, name := range names {
+		obj, act := permission.Split(name)
+		ok, err := s.enforcer.Enforce(userID, tenantID, branch, obj, act)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			permissions = append(permissions, name)
+		}
+	}
+	return permissions, nil
+}
+
+// domain returns the tenant domain, or the domain of one of its branches.
+func (s *policyService) domain(tenantID, branchID string) (string, error) {
+	if branchID == "" {
+		return tenantID, nil
+	}
+
+	if _, err := s.repo.Branch.GetByID(tenantID, branchID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrBranchNotFound
+		}
+		return "", err
+	}
+	return permission.BranchDomain(tenantID, branchID), nil
+}
+
+func (s *policyService) permissions(role, tenantID string) ([]string, error) {
+	rules, err := s.enforcer.GetFilteredPolicy(0, role, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		permissions = append(permissions, permission.Join(rule[2], rule[3]))
+	}
+	return permissions, nil
+}
+
+func (s *policyService) checkRole(tenantID, name string) error {
+	if slices.Contains(model.BuiltInRoles, name) {
+		return nil
+	}
+
+	exists, err := s.repo.Role.Exists(tenantID, name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrRoleNotFound
+	}
 	return nil
 }
+
+// checkEditableRole rejects the owner role, so owners can't lock themselves
+// out of managing the tenant.
+func (s *policyService) checkEditableRole(tenantID, name string) error {
+	if name == "owner" {
+		return ErrRoleProtected
+	}
+	return s.checkRole(tenantID, name)
+}
@@ -0,0 +1,262 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/asliddinberdiev/eirsystem/pkg/permission"
+	"github.com/casbin/casbin/v3"
+	"github.com/casbin/casbin/v3/model"
+)
+
+// Explanation tells whether a user holds a permission and which of its roles
+// and policies grant it.
+type Explanation struct {
+	Allowed    bool       `json:"allowed"`
+	Route      string     `json:"route,omitempty"`
+	Permission string     `json:"permission"`
+	Reason     string     `json:"reason"`
+	Roles      []UserRole `json:"roles"`
+	Grants     []Grant    `json:"grants"`
+}
+
+// Grant is a policy that gives one of the user's roles a permission, in the
+// whole tenant or, when BranchID is set, in that branch only.
+type Grant struct {
+	Role     string `json:"role"`
+	BranchID string `json:"branch_id,omitempty"`
+	Policy   string `json:"policy"`
+}
+
+// PolicyChange is a permission to grant to or revoke from a role.
+type PolicyChange struct {
+	Grant      bool
+	Role       string
+	Permission string
+}
+
+// AccessChange is a permission a user would gain or lose, in the whole tenant
+// or, when BranchID is set, in that branch only.
+type AccessChange struct {
+	UserID     string `json:"user_id"`
+	BranchID   string `json:"branch_id,omitempty"`
+	Permission string `json:"permission"`
+	Gained     bool   `json:"gained"`
+}
+
+// Explain tells whether the user may use a permission in the tenant, as
+// Authorizer would decide it. With a branchID it is decided for that branch
+// being the session's active branch.
+func (s *policyService) Explain(userID, tenantID, branchID, name string) (Explanation, error) {
+	explanation := Explanation{Permission: name, Grants: []Grant{}}
+
+	roles, err := s.GetUserRoles(userID, tenantID)
+	if err != nil {
+		return explanation, err
+	}
+	explanation.Roles = roles
+
+	tenantWide := false
+	var branchIDs []string
+	for _, role := range roles {
+		rules, err := s.enforcer.GetFilteredPolicy(0, role.Role, tenantID)
+		if err != nil {
+			return explanation, err
+		}
+
+		for _, rule := range rules {
+			policy := permission.Join(rule[2], rule[3])
+			if !matches(name, policy) {
+				continue
+			}
+
+			explanation.Grants = append(explanation.Grants, Grant{Role: role.Role, BranchID: role.BranchID, Policy: policy})
+			if role.BranchID == "" {
+				tenantWide = true
+			} else if !slices.Contains(branchIDs, role.BranchID) {
+				branchIDs = append(branchIDs, role.BranchID)
+			}
+		}
+	}
+
+	switch {
+	case len(roles) == 0:
+		explanation.Reason = "the user holds no role in the tenant"
+	case tenantWide:
+		explanation.Allowed = true
+		explanation.Reason = "a tenant-wide role grants " + name
+	case len(branchIDs) == 0:
+		explanation.Reason = "none of the user's roles grants " + name
+	case branchID == "":
+		explanation.Allowed = true
+		explanation.Reason = "branch roles grant " + name + " in branches " + strings.Join(branchIDs, ", ")
+	case slices.Contains(branchIDs, branchID):
+		explanation.Allowed = true
+		explanation.Reason = "a branch role grants " + name + " in the active branch"
+	default:
+		explanation.Reason = "branch roles grant " + name + " only in branches " + strings.Join(branchIDs, ", ") + ", not in the active branch"
+	}
+
+	return explanation, nil
+}
+
+// DryRun applies the changes to a copy of the tenant's policies and reports
+// which users would gain or lose which of the given permissions. A change in
+// a branch is only reported when the user doesn't hold the permission
+// tenant-wide either way.
+func (s *policyService) DryRun(tenantID string, changes []PolicyChange, names []string) ([]AccessChange, error) {
+	for _, change := range changes {
+		if err := s.checkEditableRole(tenantID, change.Role); err != nil {
+			return nil, fmt.Errorf("%s: %w", change.Role, err)
+		}
+	}
+
+	sandbox, err := s.sandbox(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	names = slices.DeleteFunc(slices.Clone(names), func(name string) bool {
+		return !slices.ContainsFunc(changes, func(change PolicyChange) bool {
+			return matches(name, change.Permission)
+		})
+	})
+
+	domains, err := userDomains(sandbox, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := access(sandbox, tenantID, domains, names)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, change := range changes {
+		obj, act := permission.Split(change.Permission)
+		if change.Grant {
+			_, err = sandbox.AddPolicy(change.Role, tenantID, obj, act)
+		} else {
+			_, err = sandbox.RemovePolicy(change.Role, tenantID, obj, act)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	after, err := access(sandbox, tenantID, domains, names)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []AccessChange{}
+	for key, allowed := range after {
+		if before[key] == allowed {
+			continue
+		}
+		if key.branch != "" && (before[accessKey{key.user, "", key.permission}] || after[accessKey{key.user, "", key.permission}]) {
+			continue
+		}
+
+		branchID, _ := permission.BranchOf(tenantID, key.branch)
+		result = append(result, AccessChange{UserID: key.user, BranchID: branchID, Permission: key.permission, Gained: allowed})
+	}
+
+	slices.SortFunc(result, func(a, b AccessChange) int {
+		return strings.Compare(a.UserID+" "+a.Permission+" "+a.BranchID, b.UserID+" "+b.Permission+" "+b.BranchID)
+	})
+	return result, nil
+}
+
+// sandbox returns an in-memory enforcer with the tenant's policies and role
+// assignments, which can be changed without touching the real ones.
+func (s *policyService) sandbox(tenantID string) (*casbin.Enforcer, error) {
+	m, err := model.NewModelFromString(s.enforcer.GetModel().ToText())
+	if err != nil {
+		return nil, err
+	}
+
+	sandbox, err := casbin.NewEnforcer(m)
+	if err != nil {
+		return nil, err
+	}
+
+	policies, err := s.enforcer.GetFilteredPolicy(1, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if len(policies) > 0 {
+		if _, err := sandbox.AddPolicies(policies); err != nil {
+			return nil, err
+		}
+	}
+
+	rules, err := s.enforcer.GetGroupingPolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	var groupings [][]string
+	for _, rule := range rules {
+		if _, inBranch := permission.BranchOf(tenantID, rule[2]); rule[2] == tenantID || inBranch {
+			groupings = append(groupings, rule)
+		}
+	}
+	if len(groupings) > 0 {
+		if _, err := sandbox.AddGroupingPolicies(groupings); err != nil {
+			return nil, err
+		}
+	}
+
+	return sandbox, nil
+}
+
+type accessKey struct {
+	user       string
+	branch     string
+	permission string
+}
+
+// userDomains lists, for every user with a role in the tenant, the branch
+// domains it holds roles in; "" stands for the tenant itself.
+func userDomains(e *casbin.Enforcer, tenantID string) (map[string][]string, error) {
+	groupings, err := e.GetGroupingPolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	domains := make(map[string][]string)
+	for _, rule := range groupings {
+		if !slices.Contains(domains[rule[0]], "") {
+			domains[rule[0]] = append(domains[rule[0]], "")
+		}
+		if rule[2] != tenantID && !slices.Contains(domains[rule[0]], rule[2]) {
+			domains[rule[0]] = append(domains[rule[0]], rule[2])
+		}
+	}
+	return domains, nil
+}
+
+func access(e *casbin.Enforcer, tenantID string, domains map[string][]string, names []string) (map[accessKey]bool, error) {
+	allowed := make(map[accessKey]bool)
+	for user, userDomains := range domains {
+		for _, domain := range userDomains {
+			for _, name := range names {
+				obj, act := permission.Split(name)
+				ok, err := e.Enforce(user, tenantID, domain, obj, act)
+				if err != nil {
+					return nil, err
+				}
+				allowed[accessKey{user, domain, name}] = ok
+			}
+		}
+	}
+	return allowed, nil
+}
+
+// matches reports whether a policy for pattern, which may use "*", covers
+// the permission name.
+func matches(name, pattern string) bool {
+	return permission.Covers(pattern, name)
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"html"
+
+	"github.com/asliddinberdiev/eirsystem/config"
+	"github.com/asliddinberdiev/eirsystem/internal/model"
+	"github.com/asliddinberdiev/eirsystem/internal/repository"
+	"github.com/asliddinberdiev/eirsystem/pkg/logger"
+	"github.com/asliddinberdiev/eirsystem/pkg/telegram"
+	"github.com/google/uuid"
+)
+
+type SecurityEvent interface {
+	Record(ctx context.Context, event model.SecurityEvent)
+}
+
+type securityEventServ struct {
+	cfg    *config.Config
+	logger logger.Logger
+	repo   *repository.Repository
+}
+
+func NewSecurityEventService(cfg *config.Config, logger logger.Logger, repo *repository.Repository) SecurityEvent {
+	return &securityEventServ{
+		cfg:    cfg,
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+// Record stores the event and alerts the operators. It never fails the
+// caller: a lost audit row must not turn a rejected refresh into a 500.
+func (s *securityEventServ) Record(ctx context.Context, event model.SecurityEvent) {
+	event.ID = uuid.New().String()
+
+	if err := s.repo.SecurityEvent.Create(&event); err != nil {
+		s.logger.Error("security event save failed", logger.Any("event", event), logger.Error(err))
+	}
+
+	s.logger.Warn("Security event", logger.String("type", event.Type), logger.String("action", event.Action), logger.String("user_id", event.UserID))
+
+	telegram.Send(fmt.Sprintf(
+		"⚠️ <b>SECURITY EVENT</b>\n\n"+
+			"🔖 <b>Type:</b> %s\n"+
+			"🛡 <b>Action:</b> %s\n"+
+			"👤 <b>User:</b> <code>%s</code>\n"+
+			"🔑 <b>Session:</b> <code>%s</code>\n"+
+			"🌐 <b>IP:</b> %s (was %s)\n"+
+			"💻 <b>User-Agent:</b> <pre>%s</pre>",
+		event.Type, event.Action, event.UserID, event.SessionID,
+		html.EscapeString(event.ClientIP), html.EscapeString(event.PreviousClientIP),
+		html.EscapeString(event.UserAgent),
+	))
+}
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/asliddinberdiev/eirsystem/config"
+	"github.com/asliddinberdiev/eirsystem/internal/model"
+	"github.com/asliddinberdiev/eirsystem/internal/repository"
+	"github.com/asliddinberdiev/eirsystem/pkg/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// APIKeyPrefix starts every API key, so it can be told apart from a JWT.
+	APIKeyPrefix = "eir_"
+
+	apiKeyPrefixSize = 5
+	apiKeySecretSize = 20
+	// apiKeyRotationGrace keeps a rotated key working while integrations switch over.
+	apiKeyRotationGrace = 24 * time.Hour
+	// apiKeyTouchInterval throttles last-use tracking to one write per key and interval.
+	apiKeyTouchInterval = time.Minute
+)
+
+var (
+	ErrServiceAccountExists = errors.New("service account with this name already exists")
+	ErrAPIKeyInvalid        = errors.New("api key is invalid")
+	ErrAPIKeyExpired        = errors.New("api key has expired or was revoked")
+)
+
+var apiKeyEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type ServiceAccount interface {
+	Create(account *model.ServiceAccount) error
+	GetAll(tenantID string) ([]model.ServiceAccount, error)
+	GetByID(tenantID, id string) (model.ServiceAccount, error)
+	Delete(tenantID, id string) error
+
+	CreateKey(key *model.APIKey) (string, error)
+	GetKeys(serviceAccountID string) ([]model.APIKey, error)
+	RevokeKey(serviceAccountID, id string) error
+	RotateKey(serviceAccountID, id string, createdBy *string) (model.APIKey, string, error)
+
+	Authenticate(ctx context.Context, rawKey, clientIP string) (model.ServiceAccount, model.APIKey, error)
+}
+
+type serviceAccountServ struct {
+	cfg    *config.Config
+	logger logger.Logger
+	repo   *repository.Repository
+}
+
+func NewServiceAccountService(cfg *config.Config, logger logger.Logger, repo *repository.Repository) ServiceAccount {
+	return &serviceAccountServ{
+		cfg:    cfg,
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+func (s *serviceAccountServ) Create(account *model.ServiceAccount) error {
+	exists, err := s.repo.ServiceAccount.ExistsByName(account.TenantID, account.Name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrServiceAccountExists
+	}
+	return s.repo.ServiceAccount.Create(account)
+}
+
+func (s *serviceAccountServ) GetAll(tenantID string) ([]model.ServiceAccount, error) {
+	return s.repo.ServiceAccount.GetAll(tenantID)
+}
+
+func (s *serviceAccountServ) GetByID(tenantID, id string) (model.ServiceAccount, error) {
+	return s.repo.ServiceAccount.GetByID(tenantID, id)
+}
+
+func (s *serviceAccountServ) Delete(tenantID, id string) error {
+	return s.repo.ServiceAccount.Delete(tenantID, id)
+}
+
+// CreateKey generates the secret of the key, stores only its hash and returns
+// the plaintext key, which cannot be recovered later.
+func (s *serviceAccountServ) CreateKey(key *model.APIKey) (string, error) {
+	rawKey, err := fillAPIKey(key)
+	if err != nil {
+		return "", err
+	}
+	return rawKey, s.repo.ServiceAccount.CreateKey(key)
+}
+
+func (s *serviceAccountServ) GetKeys(serviceAccountID string) ([]model.APIKey, error) {
+	return s.repo.ServiceAccount.GetKeys(serviceAccountID)
+}
+
+func (s *serviceAccountServ) RevokeKey(serviceAccountID, id string) error {
+	if _, err := s.repo.ServiceAccount.GetKeyByID(serviceAccountID, id); err != nil {
+		return err
+	}
+	return s.repo.ServiceAccount.RevokeKey(id)
+}
+
+// RotateKey issues a replacement with the same name, scopes and expiry and
+// lets the old key expire after a grace period.
+func (s *serviceAccountServ) RotateKey(serviceAccountID, id string, createdBy *string) (model.APIKey, string, error) {
+	old, err := s.repo.ServiceAccount.GetKeyByID(serviceAccountID, id)
+	if err != nil {
+		return model.APIKey{}, "", err
+	}
+	if old.IsExpired(time.Now()) {
+		return model.APIKey{}, "", ErrAPIKeyExpired
+	}
+
+	key := model.APIKey{
+		TenantID:         old.TenantID,
+		ServiceAccountID: old.ServiceAccountID,
+		Name:             old.Name,
+		Scopes:           old.Scopes,
+		ExpiresAt:        old.ExpiresAt,
+		CreatedBy:        createdBy,
+	}
+	rawKey, err := fillAPIKey(&key)
+	if err != nil {
+		return model.APIKey{}, "", err
+	}
+
+	if err := s.repo.ServiceAccount.RotateKey(old.ID, time.Now().Add(apiKeyRotationGrace), &key); err != nil {
+		return model.APIKey{}, "", err
+	}
+	return key, rawKey, nil
+}
+
+// Authenticate resolves the service account behind a plaintext key and records
+// when and from where the key was last used.
+func (s *serviceAccountServ) Authenticate(ctx context.Context, rawKey, clientIP string) (model.ServiceAccount, model.APIKey, error) {
+	prefix, ok := parseAPIKey(rawKey)
+	if !ok {
+		return model.ServiceAccount{}, model.APIKey{}, ErrAPIKeyInvalid
+	}
+
+	key, err := s.repo.ServiceAccount.GetKeyByPrefix(prefix)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.ServiceAccount{}, model.APIKey{}, ErrAPIKeyInvalid
+	}
+	if err != nil {
+		return model.ServiceAccount{}, model.APIKey{}, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashAPIKey(rawKey)), []byte(key.KeyHash)) != 1 {
+		return model.ServiceAccount{}, model.APIKey{}, ErrAPIKeyInvalid
+	}
+
+	now := time.Now()
+	if key.IsExpired(now) {
+		return model.ServiceAccount{}, model.APIKey{}, ErrAPIKeyExpired
+	}
+
+	account, err := s.repo.ServiceAccount.GetByID(key.TenantID, key.ServiceAccountID)
+	if err != nil {
+		return model.ServiceAccount{}, model.APIKey{}, err
+	}
+	if !account.IsActive {
+		return model.ServiceAccount{}, model.APIKey{}, ErrAPIKeyExpired
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > apiKeyTouchInterval || key.LastUsedIP != clientIP {
+		if err := s.repo.ServiceAccount.TouchKey(key.ID, clientIP); err != nil {
+			s.logger.Warn("api key last use update failed", logger.String("api_key_id", key.ID), logger.Error(err))
+		}
+	}
+
+	return account, key, nil
+}
+
+// fillAPIKey assigns the ID, lookup prefix and hash of a new key and returns
+// the plaintext "eir_<prefix>_<secret>".
+func fillAPIKey(key *model.APIKey) (string, error) {
+	random := make([]byte, apiKeyPrefixSize+apiKeySecretSize)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+
+	prefix := strings.ToLower(apiKeyEncoding.EncodeToString(random[:apiKeyPrefixSize]))
+	secret := strings.ToLower(apiKeyEncoding.EncodeToString(random[apiKeyPrefixSize:]))
+	rawKey := APIKeyPrefix + prefix + "_" + secret
+
+	key.ID = uuid.New().String()
+	key.Prefix = prefix
+	key.KeyHash = hashAPIKey(rawKey)
+	if key.Scopes == nil {
+		key.Scopes = []string{}
+	}
+	return rawKey, nil
+}
+
+func parseAPIKey(rawKey string) (string, bool) {
+	rest, ok := strings.CutPrefix(rawKey, APIKeyPrefix)
+	if !ok {
+		return "", false
+	}
+	prefix, secret, ok := strings.Cut(rest, "_")
+	if !ok || prefix == "" || secret == "" {
+		return "", false
+	}
+	return prefix, true
+}
+
+func hashAPIKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
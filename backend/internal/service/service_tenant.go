@@ -0,0 +1,298 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/asliddinberdiev/eirsystem/config"
+	"github.com/asliddinberdiev/eirsystem/internal/model"
+	"github.com/asliddinberdiev/eirsystem/internal/repository"
+	"github.com/asliddinberdiev/eirsystem/pkg/hasher"
+	"github.com/asliddinberdiev/eirsystem/pkg/logger"
+	"github.com/asliddinberdiev/eirsystem/pkg/telegram"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTenantExists       = errors.New("tenant slug is already taken")
+	ErrTenantNotFound     = errors.New("tenant not found")
+	ErrTenantSuspended    = errors.New("tenant is already suspended")
+	ErrTenantNotSuspended = errors.New("tenant is not suspended")
+)
+
+type Tenant interface {
+	GetBySlug(slug string) (model.Tenant, error)
+	Onboard(tenant *model.Tenant, branch *model.Branch, owner *model.User) (TemporaryPassword, error)
+
+	GetSubscription(ctx context.Context, tenantID string) (model.Subscription, error)
+	GetSubscriptionHistory(tenantID string) ([]model.TenantSubscriptionEvent, error)
+	Renew(ctx context.Context, tenantID string, endDate time.Time, reason string, actorID *string) (model.Tenant, error)
+	Suspend(ctx context.Context, tenantID, reason string, actorID *string) (model.Tenant, error)
+	Reactivate(ctx context.Context, tenantID, reason string, actorID *string) (model.Tenant, error)
+	RemindRenewals(ctx context.Context)
+}
+
+type tenantServ struct {
+	cfg    *config.Config
+	logger logger.Logger
+	repo   *repository.Repository
+	policy Policy
+}
+
+func NewTenantService(cfg *config.Config, logger logger.Logger, repo *repository.Repository, policy Policy) Tenant {
+	return &tenantServ{
+		cfg:    cfg,
+		logger: logger,
+		repo:   repo,
+		policy: policy,
+	}
+}
+
+func (s *tenantServ) GetBySlug(slug string) (model.Tenant, error) {
+	return s.repo.Tenant.GetBySlug(strings.ToLower(slug))
+}
+
+// Onboard creates a tenant with its first branch, its owner and its default
+// policies in one transaction. The owner gets a temporary password that must
+// be changed on the first sign-in.
+func (s *tenantServ) Onboard(tenant *model.Tenant, branch *model.Branch, owner *model.User) (TemporaryPassword, error) {
+	tenant.Slug = strings.ToLower(tenant.Slug)
+	_, err := s.repo.Tenant.GetBySlug(tenant.Slug)
+	if err == nil {
+		return TemporaryPassword{}, ErrTenantExists
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return TemporaryPassword{}, err
+	}
+
+	password, err := generateTemporaryPassword()
+	if err != nil {
+		return TemporaryPassword{}, err
+	}
+
+	passwordHash, err := hasher.Hash(password)
+	if err != nil {
+		return TemporaryPassword{}, err
+	}
+	expiresAt := time.Now().Add(TemporaryPasswordTTL)
+
+	tenant.ID = uuid.New().String()
+	tenant.IsActive = true
+
+	branch.ID = uuid.New().String()
+	branch.TenantID = tenant.ID
+
+	owner.ID = uuid.New().String()
+	owner.TenantID = tenant.ID
+	owner.Username = strings.ToLower(owner.Username)
+	owner.PasswordHash = passwordHash
+	owner.Role = "owner"
+	owner.IsActive = true
+	owner.MustChangePassword = true
+	owner.PasswordExpiresAt = &expiresAt
+
+	staff := &model.StaffProfile{
+		ID:              uuid.New().String(),
+		UserID:          owner.ID,
+		TenantID:        tenant.ID,
+		PrimaryBranchID: &branch.ID,
+	}
+
+	policies := s.policy.DefaultPolicies(tenant.ID, owner.ID)
+
+	err = s.repo.Tenant.Create(tenant, branch, owner, staff, policies)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return TemporaryPassword{}, ErrTenantExists
+	}
+	if err != nil {
+		return TemporaryPassword{}, err
+	}
+
+	// The policies are stored, so when loading them fails here the instances
+	// get them with their next policy reload.
+	if err := s.policy.LoadPolicies(policies); err != nil {
+		s.logger.Error("onboarding: loading tenant policies failed", logger.String("tenant_id", tenant.ID), logger.Error(err))
+	}
+
+	s.logger.Info("Tenant onboarded", logger.String("tenant_id", tenant.ID), logger.String("slug", tenant.Slug), logger.String("owner_id", owner.ID))
+
+	return TemporaryPassword{Password: password, ExpiresAt: expiresAt}, nil
+}
+
+func (s *tenantServ) GetSubscription(ctx context.Context, tenantID string) (model.Subscription, error) {
+	return s.repo.Tenant.GetCachedSubscription(ctx, tenantID)
+}
+
+func (s *tenantServ) GetSubscriptionHistory(tenantID string) ([]model.TenantSubscriptionEvent, error) {
+	if _, err := s.getByID(tenantID); err != nil {
+		return nil, err
+	}
+	return s.repo.Tenant.GetSubscriptionEvents(tenantID)
+}
+
+// Renew moves the end of the subscription to endDate. A suspended tenant stays
+// suspended until it is reactivated.
+func (s *tenantServ) Renew(ctx context.Context, tenantID string, endDate time.Time, reason string, actorID *string) (model.Tenant, error) {
+	return s.changeSubscription(ctx, tenantID, model.TenantRenewed, reason, actorID, func(tenant *model.Tenant) error {
+		tenant.SubscriptionEndDate = endDate
+		return nil
+	})
+}
+
+// Suspend blocks every user of the tenant regardless of the subscription end.
+func (s *tenantServ) Suspend(ctx context.Context, tenantID, reason string, actorID *string) (model.Tenant, error) {
+	return s.changeSubscription(ctx, tenantID, model.TenantSuspended, reason, actorID, func(tenant *model.Tenant) error {
+		if !tenant.IsActive {
+			return ErrTenantSuspended
+		}
+		tenant.IsActive = false
+		return nil
+	})
+}
+
+func (s *tenantServ) Reactivate(ctx context.Context, tenantID, reason string, actorID *string) (model.Tenant, error) {
+	return s.changeSubscription(ctx, tenantID, model.TenantReactivated, reason, actorID, func(tenant *model.Tenant) error {
+		if tenant.IsActive {
+			return ErrTenantNotSuspended
+		}
+		tenant.IsActive = true
+		return nil
+	})
+}
+
+func (s *tenantServ) changeSubscription(ctx context.Context, tenantID, action, reason string, actorID *string, change func(*model.Tenant) error) (model.Tenant, error) {
+	tenant, err := s.getByID(tenantID)
+	if err != nil {
+		return tenant, err
+	}
+
+	event := &model.TenantSubscriptionEvent{
+		ID:              uuid.New().String(),
+		TenantID:        tenant.ID,
+		Action:          action,
+		PreviousEndDate: tenant.SubscriptionEndDate,
+		Reason:          reason,
+		ActorID:         actorID,
+	}
+
+	if err := change(&tenant); err != nil {
+		return tenant, err
+	}
+	event.EndDate = tenant.SubscriptionEndDate
+
+	if err := s.repo.Tenant.UpdateSubscription(&tenant, event); err != nil {
+		return tenant, err
+	}
+
+	s.logger.Info("Tenant subscription changed", logger.String("tenant_id", tenant.ID), logger.String("action", action))
+
+	return tenant, s.repo.Tenant.DeleteSubscriptionCache(ctx, tenant.ID)
+}
+
+func (s *tenantServ) getByID(tenantID string) (model.Tenant, error) {
+	tenant, err := s.repo.Tenant.GetByID(tenantID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return tenant, ErrTenantNotFound
+	}
+	return tenant, err
+}
+
+// RemindRenewals checks the subscriptions every reminder interval until ctx
+// is done, and sends the operators a Telegram reminder for each tenant whose
+// subscription is about to end or has ended.
+func (s *tenantServ) RemindRenewals(ctx context.Context) {
+	interval := s.cfg.Subscription.ReminderInterval
+	if interval <= 0 {
+		s.logger.Warn("Subscription reminder interval is not set. Renewal reminders disabled.")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.remindRenewals(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// remindRenewals sends each reminder once, across instances: a reminder is
+// keyed by the tenant, its end date and the stage it is for, so a renewal
+// starts the reminders over.
+func (s *tenantServ) remindRenewals(ctx context.Context) {
+	now := time.Now()
+	grace := s.cfg.Subscription.GracePeriod
+	days := slices.Sorted(slices.Values(s.cfg.Subscription.ReminderDays))
+
+	horizon := now
+	if len(days) > 0 {
+		horizon = now.AddDate(0, 0, days[len(days)-1])
+	}
+
+	tenants, err := s.repo.Tenant.GetActiveEndingBefore(horizon)
+	if err != nil {
+		s.logger.Error("subscription reminder lookup failed", logger.Error(err))
+		return
+	}
+
+	for _, tenant := range tenants {
+		subscription := model.Subscription{TenantID: tenant.ID, IsActive: tenant.IsActive, EndDate: tenant.SubscriptionEndDate}
+		state := subscription.State(now, grace)
+
+		stage := state
+		if state == model.SubscriptionActive {
+			left := int(math.Ceil(tenant.SubscriptionEndDate.Sub(now).Hours() / 24))
+			i := slices.IndexFunc(days, func(d int) bool { return left <= d })
+			if i < 0 {
+				continue
+			}
+			stage = fmt.Sprintf("%dd", days[i])
+		}
+
+		key := fmt.Sprintf("%s:%d:%s", tenant.ID, tenant.SubscriptionEndDate.Unix(), stage)
+		ttl := tenant.SubscriptionEndDate.Add(grace).Sub(now) + 24*time.Hour
+		first, err := s.repo.Tenant.MarkReminderSent(ctx, key, ttl)
+		if err != nil {
+			s.logger.Warn("subscription reminder mark failed", logger.String("tenant_id", tenant.ID), logger.Error(err))
+			continue
+		}
+		if !first {
+			continue
+		}
+
+		telegram.Send(reminderMessage(tenant, state, grace, now))
+	}
+}
+
+func reminderMessage(tenant model.Tenant, state string, grace time.Duration, now time.Time) string {
+	var status string
+	switch state {
+	case model.SubscriptionActive:
+		status = fmt.Sprintf("ends in %d day(s)", int(math.Ceil(tenant.SubscriptionEndDate.Sub(now).Hours()/24)))
+	case model.SubscriptionGrace:
+		status = "ended, read-only until " + tenant.SubscriptionEndDate.Add(grace).Format(time.DateTime)
+	default:
+		status = "expired, access is blocked"
+	}
+
+	return fmt.Sprintf(
+		"⏰ <b>SUBSCRIPTION RENEWAL</b>\n\n"+
+			"🏥 <b>Tenant:</b> %s (<code>%s</code>)\n"+
+			"📅 <b>Ends:</b> %s\n"+
+			"📌 <b>Status:</b> %s",
+		html.EscapeString(tenant.Name), html.EscapeString(tenant.Slug),
+		tenant.SubscriptionEndDate.Format(time.DateTime), status,
+	)
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/asliddinberdiev/eirsystem/config"
+	"github.com/asliddinberdiev/eirsystem/internal/repository"
+	"github.com/asliddinberdiev/eirsystem/pkg/logger"
+	"github.com/asliddinberdiev/eirsystem/pkg/totp"
+)
+
+const (
+	pendingSecretTTL  = 10 * time.Minute
+	recoveryCodeCount = 10
+	recoveryCodeSize  = 10
+)
+
+var (
+	ErrTwoFactorInvalidCode    = errors.New("invalid two-factor code")
+	ErrTwoFactorNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorSetupExpired   = errors.New("two-factor setup has expired, start it again")
+)
+
+type TwoFactorSetup struct {
+	Secret          string
+	ProvisioningURI string
+}
+
+type TwoFactor interface {
+	Setup(ctx context.Context, userID string) (TwoFactorSetup, error)
+	Enable(ctx context.Context, userID, code string) ([]string, error)
+	Disable(ctx context.Context, userID, code string) error
+	Verify(ctx context.Context, userID, code string) error
+	VerifyRecoveryCode(ctx context.Context, userID, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error)
+
+	IsRequired(tenantID, role string) (bool, error)
+	GetRequiredRoles(tenantID string) ([]string, error)
+	SetRequiredRoles(tenantID string, roles []string) error
+}
+
+type twoFactorServ struct {
+	cfg    *config.Config
+	logger logger.Logger
+	repo   *repository.Repository
+}
+
+func NewTwoFactorService(cfg *config.Config, logger logger.Logger, repo *repository.Repository) TwoFactor {
+	return &twoFactorServ{
+		cfg:    cfg,
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+// Setup starts enrollment with a fresh secret that is kept aside until the
+// user proves the authenticator works by calling Enable with a valid code.
+func (s *twoFactorServ) Setup(ctx context.Context, userID string) (TwoFactorSetup, error) {
+	user, err := s.repo.User.GetByID(userID)
+	if err != nil {
+		return TwoFactorSetup{}, err
+	}
+	if user.TOTPEnabled {
+		return TwoFactorSetup{}, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return TwoFactorSetup{}, err
+	}
+
+	if err := s.repo.TwoFactor.SetPendingSecret(ctx, userID, secret, pendingSecretTTL); err != nil {
+		return TwoFactorSetup{}, err
+	}
+
+	return TwoFactorSetup{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(s.cfg.App.Name, user.Username, secret),
+	}, nil
+}
+
+func (s *twoFactorServ) Enable(ctx context.Context, userID, code string) ([]string, error) {
+	secret, err := s.repo.TwoFactor.GetPendingSecret(ctx, userID)
+	if err != nil {
+		return nil, ErrTwoFactorSetupExpired
+	}
+
+	if err := s.checkCode(ctx, userID, secret, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.TwoFactor.Enable(userID, secret, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, s.repo.User.DeleteCache(ctx, userID)
+}
+
+func (s *twoFactorServ) Disable(ctx context.Context, userID, code string) error {
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return err
+	}
+
+	if err := s.repo.TwoFactor.Disable(userID); err != nil {
+		return err
+	}
+
+	return s.repo.User.DeleteCache(ctx, userID)
+}
+
+func (s *twoFactorServ) Verify(ctx context.Context, userID, code string) error {
+	user, err := s.repo.User.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return ErrTwoFactorNotEnabled
+	}
+
+	return s.checkCode(ctx, userID, user.TOTPSecret, code)
+}
+
+func (s *twoFactorServ) VerifyRecoveryCode(ctx context.Context, userID, code string) error {
+	used, err := s.repo.TwoFactor.UseRecoveryCode(userID, hashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrTwoFactorInvalidCode
+	}
+	return nil
+}
+
+func (s *twoFactorServ) RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error) {
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, s.repo.TwoFactor.ReplaceRecoveryCodes(userID, hashes)
+}
+
+func (s *twoFactorServ) IsRequired(tenantID, role string) (bool, error) {
+	if tenantID == "" {
+		return false, nil
+	}
+
+	roles, err := s.repo.TwoFactor.GetRequiredRoles(tenantID)
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(roles, role), nil
+}
+
+func (s *twoFactorServ) GetRequiredRoles(tenantID string) ([]string, error) {
+	return s.repo.TwoFactor.GetRequiredRoles(tenantID)
+}
+
+func (s *twoFactorServ) SetRequiredRoles(tenantID string, roles []string) error {
+	slices.Sort(roles)
+	return s.repo.TwoFactor.SetRequiredRoles(tenantID, slices.Compact(roles))
+}
+
+func (s *twoFactorServ) checkCode(ctx context.Context, userID, secret, code string) error {
+	counter, ok := totp.Validate(secret, code, time.Now())
+	if !ok {
+		return ErrTwoFactorInvalidCode
+	}
+
+	fresh, err := s.repo.TwoFactor.MarkCodeUsed(ctx, userID, counter, 3*totp.Period)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrTwoFactorInvalidCode
+	}
+	return nil
+}
+
+// generateRecoveryCodes returns the codes to show the user once and the
+// hashes to store. The codes are random enough that a plain SHA-256 suffices.
+func generateRecoveryCodes() (codes, hashes []string, err error) {
+	for range recoveryCodeCount {
+		raw := make([]byte, recoveryCodeSize)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, err
+		}
+
+		code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(raw))[:recoveryCodeSize]
+		code = code[:5] + "-" + code[5:]
+
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
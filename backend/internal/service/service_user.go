@@ -1,6 +1,8 @@
 package service
 
 import (
+	"context"
+
 	"github.com/asliddinberdiev/eirsystem/config"
 	"github.com/asliddinberdiev/eirsystem/internal/model"
 	"github.com/asliddinberdiev/eirsystem/internal/repository"
@@ -9,9 +11,12 @@ import (
 )
 
 type User interface {
-	GetAll() ([]model.User, error)
+	GetAll(tenantID string) ([]model.User, error)
+	GetAllTenants() ([]model.User, error)
 	GetByID(id string) (model.User, error)
-	GetByUsername(username string) (model.User, error)
+	GetByUsername(tenantID, username string) (model.User, error)
+	GetSystemByUsername(username string) (model.User, error)
+	GetCachedByID(ctx context.Context, id string) (model.User, error)
 }
 
 type userServ struct {
@@ -30,14 +35,26 @@ func NewUserService(cfg *config.Config, logger logger.Logger, s3 *minio.Client,
 	}
 }
 
-func (s *userServ) GetAll() ([]model.User, error) {
-	return s.repo.User.GetAll()
+func (s *userServ) GetAll(tenantID string) ([]model.User, error) {
+	return s.repo.User.GetAll(tenantID)
+}
+
+func (s *userServ) GetAllTenants() ([]model.User, error) {
+	return s.repo.User.GetAllTenants()
 }
 
 func (s *userServ) GetByID(id string) (model.User, error) {
 	return s.repo.User.GetByID(id)
 }
 
-func (s *userServ) GetByUsername(username string) (model.User, error) {
-	return s.repo.User.GetByUsername(username)
+func (s *userServ) GetByUsername(tenantID, username string) (model.User, error) {
+	return s.repo.User.GetByUsername(tenantID, username)
+}
+
+func (s *userServ) GetSystemByUsername(username string) (model.User, error) {
+	return s.repo.User.GetSystemByUsername(username)
+}
+
+func (s *userServ) GetCachedByID(ctx context.Context, id string) (model.User, error) {
+	return s.repo.User.GetCachedByID(ctx, id)
 }
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/asliddinberdiev/eirsystem/config"
+	"github.com/asliddinberdiev/eirsystem/internal/model"
+	"github.com/asliddinberdiev/eirsystem/internal/repository"
+	"github.com/asliddinberdiev/eirsystem/pkg/logger"
+	"gorm.io/gorm"
+)
+
+type UserBlock interface {
+	Block(ctx context.Context, block *model.UserBlock) error
+	Unblock(ctx context.Context, userID string, unblockedBy *string) error
+	GetAllByUserID(userID string) ([]model.UserBlock, error)
+	ReleaseExpired(ctx context.Context, userID string) (bool, error)
+}
+
+type userBlockServ struct {
+	cfg    *config.Config
+	logger logger.Logger
+	repo   *repository.Repository
+}
+
+func NewUserBlockService(cfg *config.Config, logger logger.Logger, repo *repository.Repository) UserBlock {
+	return &userBlockServ{
+		cfg:    cfg,
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+func (s *userBlockServ) Block(ctx context.Context, block *model.UserBlock) error {
+	if err := s.repo.UserBlock.Create(block); err != nil {
+		return err
+	}
+	return s.repo.User.DeleteCache(ctx, block.UserID)
+}
+
+func (s *userBlockServ) Unblock(ctx context.Context, userID string, unblockedBy *string) error {
+	if err := s.repo.UserBlock.Release(userID, unblockedBy); err != nil {
+		return err
+	}
+	return s.repo.User.DeleteCache(ctx, userID)
+}
+
+func (s *userBlockServ) GetAllByUserID(userID string) ([]model.UserBlock, error) {
+	return s.repo.UserBlock.GetAllByUserID(userID)
+}
+
+// ReleaseExpired reactivates the user when the block that deactivated it has expired.
+// It reports false when the user is still blocked or was deactivated without a block.
+func (s *userBlockServ) ReleaseExpired(ctx context.Context, userID string) (bool, error) {
+	block, err := s.repo.UserBlock.GetActiveByUserID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if block.IsActive(time.Now()) {
+		return false, nil
+	}
+
+	if err := s.repo.UserBlock.Release(userID, nil); err != nil {
+		return false, err
+	}
+	return true, s.repo.User.DeleteCache(ctx, userID)
+}
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TABLE user_blocks (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID REFERENCES tenants(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    blocked_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    reason TEXT NOT NULL,
+    expires_at TIMESTAMP,
+    unblocked_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    unblocked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_user_blocks_user_id ON user_blocks(user_id, created_at DESC);
+
+-- +goose StatementEnd
+
+
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS user_blocks;
+
+-- +goose StatementEnd
@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TABLE security_events (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID REFERENCES tenants(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    session_id VARCHAR(36),
+    type VARCHAR(50) NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    client_ip VARCHAR(45),
+    user_agent TEXT,
+    previous_client_ip VARCHAR(45),
+    previous_user_agent TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_security_events_user_id ON security_events(user_id, created_at DESC);
+
+-- +goose StatementEnd
+
+
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS security_events;
+
+-- +goose StatementEnd
@@ -0,0 +1,44 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE users
+    ADD COLUMN must_change_password BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN password_expires_at TIMESTAMP,
+    ADD COLUMN password_changed_at TIMESTAMP;
+
+CREATE TABLE user_password_histories (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    password_hash VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_user_password_histories_user_id ON user_password_histories(user_id, created_at DESC);
+
+CREATE TABLE tenant_password_policies (
+    tenant_id UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+    min_length INT NOT NULL DEFAULT 8,
+    require_upper BOOLEAN NOT NULL DEFAULT FALSE,
+    require_lower BOOLEAN NOT NULL DEFAULT FALSE,
+    require_digit BOOLEAN NOT NULL DEFAULT FALSE,
+    require_symbol BOOLEAN NOT NULL DEFAULT FALSE,
+    history_size INT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose StatementEnd
+
+
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS tenant_password_policies;
+DROP TABLE IF EXISTS user_password_histories;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS password_changed_at,
+    DROP COLUMN IF EXISTS password_expires_at,
+    DROP COLUMN IF EXISTS must_change_password;
+
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_username_key;
+
+CREATE UNIQUE INDEX idx_users_tenant_username ON users(tenant_id, username);
+CREATE UNIQUE INDEX idx_users_system_username ON users(username) WHERE role = 'system';
+
+-- +goose StatementEnd
+
+
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_users_system_username;
+DROP INDEX IF EXISTS idx_users_tenant_username;
+
+ALTER TABLE users ADD CONSTRAINT users_username_key UNIQUE (username);
+
+-- +goose StatementEnd
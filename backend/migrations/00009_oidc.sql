@@ -0,0 +1,43 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TABLE tenant_oidc_providers (
+    tenant_id UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+    issuer TEXT NOT NULL,
+    client_id VARCHAR(255) NOT NULL,
+    client_secret TEXT,
+    redirect_url TEXT NOT NULL,
+    scopes JSONB NOT NULL DEFAULT '["openid", "profile", "email"]',
+    username_claim VARCHAR(100) NOT NULL DEFAULT 'preferred_username',
+    name_claim VARCHAR(100) NOT NULL DEFAULT 'name',
+    groups_claim VARCHAR(100) NOT NULL DEFAULT 'groups',
+    role_mapping JSONB NOT NULL DEFAULT '{}',
+    default_role user_role,
+    auto_provision BOOLEAN NOT NULL DEFAULT FALSE,
+    is_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE user_identities (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    issuer TEXT NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(tenant_id, issuer, subject)
+);
+
+CREATE INDEX idx_user_identities_user_id ON user_identities(user_id);
+
+-- +goose StatementEnd
+
+
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS user_identities;
+DROP TABLE IF EXISTS tenant_oidc_providers;
+
+-- +goose StatementEnd
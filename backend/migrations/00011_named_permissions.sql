@@ -0,0 +1,173 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Policies grant named permissions ("resource:action", stored as v2 and v3)
+-- instead of URL paths and methods, and roles lose the "role:" prefix that
+-- the default tenant policies used. casbin_rule is created by the Casbin
+-- adapter at startup, so a fresh database has no policies to migrate. API key
+-- scopes become permission names the same way.
+DO $$
+BEGIN
+    CREATE TEMP TABLE route_permissions (path, method, resource, action) ON COMMIT DROP AS
+        VALUES
+            ('/api/v1/users', 'GET', 'users', 'read'),
+            ('/api/v1/users/:id/blocks', 'GET', 'users', 'read'),
+            ('/api/v1/users/:id/block', 'POST', 'users', 'block'),
+            ('/api/v1/users/:id/unblock', 'POST', 'users', 'block'),
+            ('/api/v1/users/:id/lockout', 'DELETE', 'users', 'unlock'),
+            ('/api/v1/users/:id/password/reset', 'POST', 'users', 'reset-password'),
+            ('/api/v1/users/:id/impersonate', 'POST', 'users', 'impersonate'),
+            ('/api/v1/tenant/two-factor', 'GET', 'tenant-settings', 'read'),
+            ('/api/v1/tenant/two-factor', 'PUT', 'tenant-settings', 'update'),
+            ('/api/v1/tenant/password-policy', 'GET', 'tenant-settings', 'read'),
+            ('/api/v1/tenant/password-policy', 'PUT', 'tenant-settings', 'update'),
+            ('/api/v1/tenant/sso', 'GET', 'tenant-settings', 'read'),
+            ('/api/v1/tenant/sso', 'PUT', 'tenant-settings', 'update'),
+            ('/api/v1/tenant/sso', 'DELETE', 'tenant-settings', 'update'),
+            ('/api/v1/service-accounts', 'GET', 'service-accounts', 'read'),
+            ('/api/v1/service-accounts', 'POST', 'service-accounts', 'manage'),
+            ('/api/v1/service-accounts/:id', 'DELETE', 'service-accounts', 'manage'),
+            ('/api/v1/service-accounts/:id/keys', 'GET', 'service-accounts', 'read'),
+            ('/api/v1/service-accounts/:id/keys', 'POST', 'service-accounts', 'manage'),
+            ('/api/v1/service-accounts/:id/keys/:keyId/rotate', 'POST', 'service-accounts', 'manage'),
+            ('/api/v1/service-accounts/:id/keys/:keyId', 'DELETE', 'service-accounts', 'manage'),
+            ('/api/v1/roles', 'GET', 'roles', 'read'),
+            ('/api/v1/roles', 'POST', 'roles', 'manage'),
+            ('/api/v1/roles/:role', 'DELETE', 'roles', 'manage'),
+            ('/api/v1/roles/:role/permissions', 'POST', 'roles', 'manage'),
+            ('/api/v1/roles/:role/permissions', 'DELETE', 'roles', 'manage'),
+            ('/api/v1/permissions', 'GET', 'roles', 'read'),
+            ('/api/v1/users/:id/roles', 'GET', 'roles', 'read'),
+            ('/api/v1/users/:id/roles', 'POST', 'roles', 'assign'),
+            ('/api/v1/users/:id/roles/:role', 'DELETE', 'roles', 'assign'),
+            ('/api/v1/patients', 'GET', 'patients', 'read'),
+            ('/api/v1/appointments', 'POST', 'appointments', 'create'),
+            ('/api/v1/vitals', 'POST', 'vitals', 'create'),
+            ('/api/v1/test/owner', 'GET', 'test', 'owner'),
+            ('/api/v1/test/doctor', 'GET', 'test', 'doctor'),
+            ('/api/v1/test/nurse', 'GET', 'test', 'nurse'),
+            ('/api/v1/*', 'GET', '*', '*'),
+            ('/api/v1/*', 'POST', '*', '*'),
+            ('/api/v1/*', 'DELETE', '*', '*');
+
+    IF to_regclass('casbin_rule') IS NOT NULL THEN
+        INSERT INTO casbin_rule (ptype, v0, v1, v2, v3, v4, v5)
+        SELECT DISTINCT 'p', regexp_replace(r.v0, '^role:', ''), r.v1, m.resource, m.action, '', ''
+        FROM casbin_rule r
+        JOIN route_permissions m ON m.path = r.v2 AND m.method = r.v3
+        WHERE r.ptype = 'p'
+        ON CONFLICT DO NOTHING;
+
+        -- Path rules without a matching permission can't match any request anymore.
+        DELETE FROM casbin_rule WHERE ptype = 'p' AND v2 LIKE '/%';
+
+        DELETE FROM casbin_rule r
+        WHERE r.ptype = 'g' AND r.v1 LIKE 'role:%'
+          AND EXISTS (SELECT 1 FROM casbin_rule o WHERE o.ptype = 'g' AND o.v0 = r.v0 AND o.v1 = substr(r.v1, 6) AND o.v2 = r.v2);
+        UPDATE casbin_rule SET v1 = substr(v1, 6) WHERE ptype = 'g' AND v1 LIKE 'role:%';
+    END IF;
+
+    -- A scope was "METHOD /api/v1/path" with keyMatch2 patterns and "*" for any
+    -- method; it becomes the permissions of the routes it matched. The
+    -- catch-all routes are left out so a narrow scope doesn't turn into "*:*".
+    -- A key none of whose scopes matched a route could not be used on any of
+    -- them, so it is revoked rather than left with scopes that mean nothing.
+    UPDATE api_keys k
+    SET scopes = mapped.scopes
+    FROM (
+        SELECT k.id, jsonb_agg(DISTINCT m.resource || ':' || m.action) AS scopes
+        FROM api_keys k
+        CROSS JOIN jsonb_array_elements_text(k.scopes) AS s (scope)
+        JOIN route_permissions m
+          ON split_part(s.scope, ' ', 1) IN ('*', m.method)
+         AND m.path NOT LIKE '%*'
+         AND regexp_replace(m.path, ':[^/]+', 'x', 'g')
+             ~ ('^' || regexp_replace(regexp_replace(split_part(s.scope, ' ', 2), '/\*', '/.*', 'g'), ':[^/]+', '[^/]+', 'g') || '$')
+        GROUP BY k.id
+    ) AS mapped
+    WHERE k.id = mapped.id;
+
+    UPDATE api_keys
+    SET revoked_at = CURRENT_TIMESTAMP
+    WHERE revoked_at IS NULL
+      AND EXISTS (SELECT 1 FROM jsonb_array_elements_text(scopes) AS s (scope) WHERE s.scope LIKE '% /%');
+END $$;
+
+-- +goose StatementEnd
+
+
+
+-- +goose Down
+-- +goose StatementBegin
+
+DO $$
+BEGIN
+    CREATE TEMP TABLE route_permissions (path, method, resource, action) ON COMMIT DROP AS
+        VALUES
+            ('/api/v1/users', 'GET', 'users', 'read'),
+            ('/api/v1/users/:id/blocks', 'GET', 'users', 'read'),
+            ('/api/v1/users/:id/block', 'POST', 'users', 'block'),
+            ('/api/v1/users/:id/unblock', 'POST', 'users', 'block'),
+            ('/api/v1/users/:id/lockout', 'DELETE', 'users', 'unlock'),
+            ('/api/v1/users/:id/password/reset', 'POST', 'users', 'reset-password'),
+            ('/api/v1/users/:id/impersonate', 'POST', 'users', 'impersonate'),
+            ('/api/v1/tenant/two-factor', 'GET', 'tenant-settings', 'read'),
+            ('/api/v1/tenant/two-factor', 'PUT', 'tenant-settings', 'update'),
+            ('/api/v1/tenant/password-policy', 'GET', 'tenant-settings', 'read'),
+            ('/api/v1/tenant/password-policy', 'PUT', 'tenant-settings', 'update'),
+            ('/api/v1/tenant/sso', 'GET', 'tenant-settings', 'read'),
+            ('/api/v1/tenant/sso', 'PUT', 'tenant-settings', 'update'),
+            ('/api/v1/tenant/sso', 'DELETE', 'tenant-settings', 'update'),
+            ('/api/v1/service-accounts', 'GET', 'service-accounts', 'read'),
+            ('/api/v1/service-accounts', 'POST', 'service-accounts', 'manage'),
+            ('/api/v1/service-accounts/:id', 'DELETE', 'service-accounts', 'manage'),
+            ('/api/v1/service-accounts/:id/keys', 'GET', 'service-accounts', 'read'),
+            ('/api/v1/service-accounts/:id/keys', 'POST', 'service-accounts', 'manage'),
+            ('/api/v1/service-accounts/:id/keys/:keyId/rotate', 'POST', 'service-accounts', 'manage'),
+            ('/api/v1/service-accounts/:id/keys/:keyId', 'DELETE', 'service-accounts', 'manage'),
+            ('/api/v1/roles', 'GET', 'roles', 'read'),
+            ('/api/v1/roles', 'POST', 'roles', 'manage'),
+            ('/api/v1/roles/:role', 'DELETE', 'roles', 'manage'),
+            ('/api/v1/roles/:role/permissions', 'POST', 'roles', 'manage'),
+            ('/api/v1/roles/:role/permissions', 'DELETE', 'roles', 'manage'),
+            ('/api/v1/permissions', 'GET', 'roles', 'read'),
+            ('/api/v1/users/:id/roles', 'GET', 'roles', 'read'),
+            ('/api/v1/users/:id/roles', 'POST', 'roles', 'assign'),
+            ('/api/v1/users/:id/roles/:role', 'DELETE', 'roles', 'assign'),
+            ('/api/v1/patients', 'GET', 'patients', 'read'),
+            ('/api/v1/appointments', 'POST', 'appointments', 'create'),
+            ('/api/v1/vitals', 'POST', 'vitals', 'create'),
+            ('/api/v1/test/owner', 'GET', 'test', 'owner'),
+            ('/api/v1/test/doctor', 'GET', 'test', 'doctor'),
+            ('/api/v1/test/nurse', 'GET', 'test', 'nurse'),
+            ('/api/v1/*', 'GET', '*', '*'),
+            ('/api/v1/*', 'POST', '*', '*'),
+            ('/api/v1/*', 'DELETE', '*', '*');
+
+    IF to_regclass('casbin_rule') IS NOT NULL THEN
+        INSERT INTO casbin_rule (ptype, v0, v1, v2, v3, v4, v5)
+        SELECT DISTINCT 'p', r.v0, r.v1, m.path, m.method, '', ''
+        FROM casbin_rule r
+        JOIN route_permissions m ON m.resource = r.v2 AND m.action = r.v3
+        WHERE r.ptype = 'p'
+        ON CONFLICT DO NOTHING;
+
+        DELETE FROM casbin_rule WHERE ptype = 'p' AND v2 NOT LIKE '/%';
+    END IF;
+
+    UPDATE api_keys k
+    SET scopes = mapped.scopes
+    FROM (
+        SELECT k.id, jsonb_agg(DISTINCT m.method || ' ' || m.path) AS scopes
+        FROM api_keys k
+        CROSS JOIN jsonb_array_elements_text(k.scopes) AS s (scope)
+        JOIN route_permissions m
+          ON split_part(s.scope, ':', 1) IN ('*', m.resource)
+         AND split_part(s.scope, ':', 2) IN ('*', m.action)
+         AND m.path NOT LIKE '%*'
+        GROUP BY k.id
+    ) AS mapped
+    WHERE k.id = mapped.id;
+END $$;
+
+-- +goose StatementEnd
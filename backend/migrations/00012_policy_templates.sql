@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TABLE tenant_policy_templates (
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    role VARCHAR(50) NOT NULL,
+    version INT NOT NULL,
+    permissions JSONB NOT NULL DEFAULT '[]',
+    applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tenant_id, role)
+);
+
+-- Nothing is recorded for existing tenants: they never got the template
+-- permissions, only the test routes. The first start after this migration
+-- grants them every template in full, keeping the rules they already hold.
+
+-- +goose StatementEnd
+
+
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS tenant_policy_templates;
+
+-- +goose StatementEnd
@@ -0,0 +1,68 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Tenant tables only show the rows of the tenant the transaction is scoped to
+-- (app.tenant_id), or every row when app.bypass_rls is on. The application
+-- sets both per transaction; a session that sets neither sees nothing.
+-- FORCE makes the policies apply to the table owner too, which is the role the
+-- application connects as. Migrations that change tenant rows run with the
+-- bypass on: the application opens them with it, and when they are run by hand
+-- the connection needs "options=-c app.bypass_rls=on".
+CREATE FUNCTION rls_bypass() RETURNS BOOLEAN
+LANGUAGE sql STABLE AS $$
+    SELECT COALESCE(current_setting('app.bypass_rls', true), '') = 'on'
+$$;
+
+CREATE FUNCTION rls_tenant() RETURNS UUID
+LANGUAGE sql STABLE AS $$
+    SELECT NULLIF(current_setting('app.tenant_id', true), '')::UUID
+$$;
+
+DO $$
+DECLARE
+    tbl TEXT;
+BEGIN
+    FOREACH tbl IN ARRAY ARRAY[
+        'branches', 'users', 'staff_profiles', 'patients', 'appointments',
+        'services', 'payments', 'products', 'lab_orders', 'user_blocks',
+        'tenant_two_factor_roles', 'security_events', 'tenant_password_policies',
+        'service_accounts', 'api_keys', 'impersonations', 'tenant_oidc_providers',
+        'user_identities', 'tenant_roles', 'tenant_policy_templates'
+    ] LOOP
+        EXECUTE format('ALTER TABLE %I ENABLE ROW LEVEL SECURITY', tbl);
+        EXECUTE format('ALTER TABLE %I FORCE ROW LEVEL SECURITY', tbl);
+        EXECUTE format(
+            'CREATE POLICY tenant_isolation ON %I USING (rls_bypass() OR tenant_id = rls_tenant()) WITH CHECK (rls_bypass() OR tenant_id = rls_tenant())',
+            tbl
+        );
+    END LOOP;
+END $$;
+
+-- +goose StatementEnd
+
+
+
+-- +goose Down
+-- +goose StatementBegin
+
+DO $$
+DECLARE
+    tbl TEXT;
+BEGIN
+    FOREACH tbl IN ARRAY ARRAY[
+        'branches', 'users', 'staff_profiles', 'patients', 'appointments',
+        'services', 'payments', 'products', 'lab_orders', 'user_blocks',
+        'tenant_two_factor_roles', 'security_events', 'tenant_password_policies',
+        'service_accounts', 'api_keys', 'impersonations', 'tenant_oidc_providers',
+        'user_identities', 'tenant_roles', 'tenant_policy_templates'
+    ] LOOP
+        EXECUTE format('DROP POLICY IF EXISTS tenant_isolation ON %I', tbl);
+        EXECUTE format('ALTER TABLE %I NO FORCE ROW LEVEL SECURITY', tbl);
+        EXECUTE format('ALTER TABLE %I DISABLE ROW LEVEL SECURITY', tbl);
+    END LOOP;
+END $$;
+
+DROP FUNCTION IF EXISTS rls_tenant();
+DROP FUNCTION IF EXISTS rls_bypass();
+
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- owner_id was never linked: seeded tenants got a random id that matches no
+-- user. Point each tenant at its first owner account, then let the column
+-- reference users so it can't drift again.
+UPDATE tenants t SET owner_id = (
+    SELECT u.id FROM users u
+    WHERE u.tenant_id = t.id AND u.role = 'owner'
+    ORDER BY u.created_at
+    LIMIT 1
+);
+
+ALTER TABLE tenants
+    ADD CONSTRAINT tenants_owner_id_fkey FOREIGN KEY (owner_id) REFERENCES users(id) ON DELETE SET NULL;
+
+-- +goose StatementEnd
+
+
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE tenants DROP CONSTRAINT IF EXISTS tenants_owner_id_fkey;
+
+-- +goose StatementEnd
@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TABLE tenant_subscription_events (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    action VARCHAR(20) NOT NULL,
+    previous_end_date TIMESTAMP,
+    end_date TIMESTAMP,
+    reason TEXT NOT NULL,
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_tenant_subscription_events_tenant_id ON tenant_subscription_events(tenant_id, created_at DESC);
+
+ALTER TABLE tenant_subscription_events ENABLE ROW LEVEL SECURITY;
+ALTER TABLE tenant_subscription_events FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON tenant_subscription_events
+    USING (rls_bypass() OR tenant_id = rls_tenant())
+    WITH CHECK (rls_bypass() OR tenant_id = rls_tenant());
+
+-- +goose StatementEnd
+
+
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS tenant_subscription_events;
+
+-- +goose StatementEnd
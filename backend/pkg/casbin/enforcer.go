@@ -7,13 +7,13 @@ import (
 	"gorm.io/gorm"
 )
 
-func InitEnforcer(db *gorm.DB, modelPath string) (*casbin.Enforcer, error) {
+func InitEnforcer(db *gorm.DB, modelPath string) (*casbin.SyncedEnforcer, error) {
 	adapter, err := gormadapter.NewAdapterByDB(db)
 	if err != nil {
 		return nil, err
 	}
 
-	enforcer, err := casbin.NewEnforcer(modelPath, adapter)
+	enforcer, err := casbin.NewSyncedEnforcer(modelPath, adapter)
 	if err != nil {
 		return nil, err
 	}
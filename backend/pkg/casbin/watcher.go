@@ -0,0 +1,300 @@
+package casbin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asliddinberdiev/eirsystem/pkg/logger"
+	"github.com/casbin/casbin/v3"
+	"github.com/casbin/casbin/v3/model"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	watcherChannel    = "casbin:policy"
+	watcherVersionKey = "casbin:policy:version"
+
+	watcherRetryDelay = time.Second
+)
+
+const (
+	opAddPolicies    = "add_policies"
+	opRemovePolicies = "remove_policies"
+	opRemoveFiltered = "remove_filtered_policy"
+	opReload         = "reload"
+)
+
+// publishScript numbers every change and publishes it in one step, so the
+// numbers arrive in order and a gap means a change was missed.
+var publishScript = redis.NewScript(`
+local version = redis.call('INCR', KEYS[1])
+redis.call('PUBLISH', ARGV[1], version .. ' ' .. ARGV[2])
+return version
+`)
+
+type watcherMessage struct {
+	Instance    string     `json:"instance"`
+	Op          string     `json:"op"`
+	Sec         string     `json:"sec,omitempty"`
+	Ptype       string     `json:"ptype,omitempty"`
+	Rules       [][]string `json:"rules,omitempty"`
+	FieldIndex  int        `json:"field_index,omitempty"`
+	FieldValues []string   `json:"field_values,omitempty"`
+}
+
+// Watcher keeps the policy of every backend instance in sync. Each change made
+// through the enforcer is published over Redis and applied to the in-memory
+// policy of the other instances; the database was already written by the
+// instance that made the change.
+//
+// Changes are numbered. When a number is skipped, or the subscription had to
+// be re-established, the instance may have missed changes and reloads the
+// whole policy from the database instead.
+type Watcher struct {
+	log      logger.Logger
+	client   *redis.Client
+	enforcer *casbin.SyncedEnforcer
+	instance string
+
+	mu      sync.Mutex
+	reload  func(string)
+	version int64
+
+	pubsub *redis.PubSub
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewWatcher subscribes to policy changes and attaches the watcher to the
+// enforcer, so that its changes are published too.
+func NewWatcher(log logger.Logger, client *redis.Client, enforcer *casbin.SyncedEnforcer) (*Watcher, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	w := &Watcher{
+		log:      log,
+		client:   client,
+		enforcer: enforcer,
+		instance: uuid.New().String(),
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	w.reload = func(string) {
+		if err := enforcer.LoadPolicy(); err != nil {
+			log.Error("Policy reload failed", logger.Error(err))
+		}
+	}
+
+	w.pubsub = client.Subscribe(ctx, watcherChannel)
+	if _, err := w.pubsub.Receive(ctx); err != nil {
+		cancel()
+		w.pubsub.Close()
+		return nil, fmt.Errorf("policy watcher subscribe failed: %w", err)
+	}
+	// The policy was loaded before subscribing; anything changed meanwhile
+	// would be missed, so start from a fresh load.
+	w.resync(ctx)
+
+	if err := enforcer.SetWatcher(w); err != nil {
+		cancel()
+		w.pubsub.Close()
+		return nil, err
+	}
+
+	go w.listen(ctx)
+
+	return w, nil
+}
+
+// SetUpdateCallback replaces the function that reloads the whole policy.
+func (w *Watcher) SetUpdateCallback(callback func(string)) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.reload = callback
+	return nil
+}
+
+// Update asks the other instances to reload the whole policy.
+func (w *Watcher) Update() error {
+	return w.publish(watcherMessage{Op: opReload})
+}
+
+func (w *Watcher) UpdateForAddPolicy(sec, ptype string, params ...string) error {
+	return w.UpdateForAddPolicies(sec, ptype, params)
+}
+
+func (w *Watcher) UpdateForRemovePolicy(sec, ptype string, params ...string) error {
+	return w.UpdateForRemovePolicies(sec, ptype, params)
+}
+
+func (w *Watcher) UpdateForRemoveFilteredPolicy(sec, ptype string, fieldIndex int, fieldValues ...string) error {
+	return w.publish(watcherMessage{Op: opRemoveFiltered, Sec: sec, Ptype: ptype, FieldIndex: fieldIndex, FieldValues: fieldValues})
+}
+
+func (w *Watcher) UpdateForSavePolicy(model.Model) error {
+	return w.Update()
+}
+
+func (w *Watcher) UpdateForAddPolicies(sec string, ptype string, rules ...[]string) error {
+	return w.publish(watcherMessage{Op: opAddPolicies, Sec: sec, Ptype: ptype, Rules: rules})
+}
+
+func (w *Watcher) UpdateForRemovePolicies(sec string, ptype string, rules ...[]string) error {
+	return w.publish(watcherMessage{Op: opRemovePolicies, Sec: sec, Ptype: ptype, Rules: rules})
+}
+
+// Close stops listening for changes and waits for the listener to exit.
+func (w *Watcher) Close() {
+	w.cancel()
+	// A blocked Receive only returns once the connection is closed.
+	w.pubsub.Close()
+	<-w.done
+}
+
+func (w *Watcher) publish(msg watcherMessage) error {
+	msg.Instance = w.instance
+
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := publishScript.Run(ctx, w.client, []string{watcherVersionKey}, watcherChannel, string(payload)).Err(); err != nil {
+		// The change is saved, but the other instances only see it after
+		// their next reload.
+		w.log.Error("Policy change publish failed", logger.String("op", msg.Op), logger.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (w *Watcher) listen(ctx context.Context) {
+	defer close(w.done)
+
+	for {
+		received, err := w.pubsub.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			// The next Receive reconnects and subscribes again.
+			w.log.Warn("Policy watcher connection lost", logger.Error(err))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(watcherRetryDelay):
+			}
+			continue
+		}
+
+		switch m := received.(type) {
+		case *redis.Subscription:
+			if m.Kind == "subscribe" {
+				w.log.Info("Policy watcher resubscribed, reloading policy")
+				w.resync(ctx)
+			}
+		case *redis.Message:
+			w.handle(ctx, m.Payload)
+		}
+	}
+}
+
+// resync reloads the whole policy. The version is read first, so changes made
+// during the load are applied again afterwards, which is harmless.
+func (w *Watcher) resync(ctx context.Context) {
+	version, err := w.client.Get(ctx, watcherVersionKey).Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		w.log.Warn("Policy version read failed", logger.Error(err))
+	}
+
+	w.mu.Lock()
+	w.version = version
+	reload := w.reload
+	w.mu.Unlock()
+
+	reload("")
+}
+
+func (w *Watcher) handle(ctx context.Context, payload string) {
+	rawVersion, body, _ := strings.Cut(payload, " ")
+	version, err := strconv.ParseInt(rawVersion, 10, 64)
+	if err != nil {
+		w.log.Warn("Policy watcher received a malformed message", logger.String("payload", payload))
+		return
+	}
+
+	w.mu.Lock()
+	expected := w.version + 1
+	if version >= expected {
+		w.version = version
+	}
+	w.mu.Unlock()
+
+	// Older changes were published before the last reload read the database.
+	if version < expected {
+		return
+	}
+	if version > expected {
+		w.log.Warn("Policy changes were missed, reloading policy", logger.Any("expected", expected), logger.Any("received", version))
+		w.resync(ctx)
+		return
+	}
+
+	var msg watcherMessage
+	if err := json.Unmarshal([]byte(body), &msg); err != nil {
+		w.log.Warn("Policy watcher received a malformed message", logger.String("payload", payload))
+		w.resync(ctx)
+		return
+	}
+	if msg.Instance == w.instance {
+		return
+	}
+
+	if err := w.apply(msg); err != nil {
+		w.log.Warn("Policy change could not be applied, reloading policy", logger.String("op", msg.Op), logger.Error(err))
+		w.resync(ctx)
+	}
+}
+
+// apply changes the in-memory policy only. Auto-save is switched off while the
+// enforcer lock is held, so no other change can slip through unsaved.
+func (w *Watcher) apply(msg watcherMessage) error {
+	if msg.Op == opReload {
+		w.mu.Lock()
+		reload := w.reload
+		w.mu.Unlock()
+
+		reload("")
+		return nil
+	}
+
+	lock := w.enforcer.GetLock()
+	lock.Lock()
+	defer lock.Unlock()
+
+	w.enforcer.EnableAutoSave(false)
+	defer w.enforcer.EnableAutoSave(true)
+
+	var err error
+	switch msg.Op {
+	case opAddPolicies:
+		_, err = w.enforcer.Enforcer.SelfAddPoliciesEx(msg.Sec, msg.Ptype, msg.Rules)
+	case opRemovePolicies:
+		_, err = w.enforcer.Enforcer.SelfRemovePolicies(msg.Sec, msg.Ptype, msg.Rules)
+	case opRemoveFiltered:
+		_, err = w.enforcer.Enforcer.SelfRemoveFilteredPolicy(msg.Sec, msg.Ptype, msg.FieldIndex, msg.FieldValues...)
+	default:
+		err = fmt.Errorf("unknown op %q", msg.Op)
+	}
+	return err
+}
@@ -15,10 +15,15 @@ const (
 	TooManyRequests Code = 429
 
 	// USER -> 1000 - 1999
-	UserNotFound      Code = 1001
-	UserAlreadyExists Code = 1002
-	UserPasswordWrong Code = 1003
-	UserInactive      Code = 1004
+	UserNotFound           Code = 1001
+	UserAlreadyExists      Code = 1002
+	UserPasswordWrong      Code = 1003
+	UserInactive           Code = 1004
+	UserActionForbidden    Code = 1005
+	PasswordPolicyWeak     Code = 1006
+	PasswordReused         Code = 1007
+	ServiceAccountNotFound Code = 1008
+	ServiceAccountExists   Code = 1009
 
 	// AUTH -> 2000 - 2999
 	AuthTokenExpired        Code = 2001
@@ -29,21 +34,68 @@ const (
 	SessionRevoked          Code = 2006
 	SessionMismatch         Code = 2007
 	AuthAccessTokenRequired Code = 2008
+	SessionNotFound         Code = 2009
+	AuthChallengeInvalid    Code = 2010
+	TwoFactorRequired       Code = 2011
+	TwoFactorSetupRequired  Code = 2012
+	TwoFactorInvalidCode    Code = 2013
+	TwoFactorNotEnabled     Code = 2014
+	TwoFactorAlreadyEnabled Code = 2015
+	TwoFactorSetupExpired   Code = 2016
+	RefreshTokenReused      Code = 2017
+	SessionContextChanged   Code = 2018
+	AuthLockedOut           Code = 2019
+	PasswordChangeRequired  Code = 2020
+	TempPasswordExpired     Code = 2021
+	APIKeyInvalid           Code = 2022
+	APIKeyExpired           Code = 2023
+	APIKeyScopeDenied       Code = 2024
+	APIKeyNotFound          Code = 2025
+	APIKeyNotAllowed        Code = 2026
+	ImpersonationForbidden  Code = 2027
+	ImpersonationNotAllowed Code = 2028
+	SSOStateInvalid         Code = 2029
+	SSOLoginFailed          Code = 2030
+	SSOUserNotProvisioned   Code = 2031
+	SSORoleUnmapped         Code = 2032
+
+	// TENANT -> 3000 - 3999
+	TenantRequired       Code = 3001
+	TenantNotFound       Code = 3002
+	TenantInactive       Code = 3003
+	SSONotConfigured     Code = 3004
+	SSOProviderInvalid   Code = 3005
+	BranchNotFound       Code = 3006
+	BranchAccessDenied   Code = 3007
+	TenantExists         Code = 3008
+	SubscriptionReadOnly Code = 3009
+	SubscriptionExpired  Code = 3010
+
+	// ACCESS -> 4000 - 4999
+	RoleNotFound      Code = 4001
+	RoleExists        Code = 4002
+	RoleProtected     Code = 4003
+	PermissionUnknown Code = 4004
+	PermissionDenied  Code = 4005
 )
 
 func (c Code) HTTPStatus() int {
 	switch c {
-	case Ok:
+	case Ok, TwoFactorRequired, TwoFactorSetupRequired:
 		return http.StatusOK
-	case TooManyRequests:
+	case SubscriptionExpired:
+		return http.StatusPaymentRequired
+	case TooManyRequests, AuthLockedOut:
 		return http.StatusTooManyRequests
 	case InternalError:
 		return http.StatusInternalServerError
-	case InvalidRequest, UserAlreadyExists, UserPasswordWrong, AuthAccessTokenRequired:
+	case InvalidRequest, UserAlreadyExists, UserPasswordWrong, PasswordPolicyWeak, PasswordReused, AuthAccessTokenRequired, TwoFactorNotEnabled, TwoFactorAlreadyEnabled, TwoFactorSetupExpired, TenantRequired, ServiceAccountExists, SSOProviderInvalid, RoleExists, PermissionUnknown, TenantExists:
 		return http.StatusBadRequest
-	case UserNotFound:
+	case UserNotFound, SessionNotFound, TenantNotFound, ServiceAccountNotFound, APIKeyNotFound, SSONotConfigured, RoleNotFound, BranchNotFound:
 		return http.StatusNotFound
-	case AuthTokenExpired, AuthTokenInvalid, AuthRequired, AuthInvalidCredentials, UserBlocked, SessionRevoked, SessionMismatch:
+	case UserActionForbidden, PasswordChangeRequired, TenantInactive, APIKeyScopeDenied, APIKeyNotAllowed, ImpersonationForbidden, ImpersonationNotAllowed, SSOUserNotProvisioned, SSORoleUnmapped, RoleProtected, BranchAccessDenied, PermissionDenied, SubscriptionReadOnly:
+		return http.StatusForbidden
+	case AuthTokenExpired, AuthTokenInvalid, AuthRequired, AuthInvalidCredentials, UserBlocked, UserInactive, SessionRevoked, SessionMismatch, AuthChallengeInvalid, TwoFactorInvalidCode, RefreshTokenReused, SessionContextChanged, TempPasswordExpired, APIKeyInvalid, APIKeyExpired, SSOStateInvalid, SSOLoginFailed:
 		return http.StatusUnauthorized
 	default:
 		return http.StatusInternalServerError
@@ -71,6 +123,16 @@ func (c Code) String() string {
 		return "Incorrect password"
 	case UserInactive:
 		return "User account is inactive"
+	case UserActionForbidden:
+		return "Action is not allowed for this user"
+	case PasswordPolicyWeak:
+		return "Password does not meet the password policy"
+	case PasswordReused:
+		return "Password was used recently"
+	case ServiceAccountNotFound:
+		return "Service account not found"
+	case ServiceAccountExists:
+		return "Service account already exists"
 
 	// AUTH
 	case AuthTokenExpired:
@@ -89,6 +151,88 @@ func (c Code) String() string {
 		return "Session mismatch"
 	case AuthAccessTokenRequired:
 		return "Authorization header required"
+	case SessionNotFound:
+		return "Session not found"
+	case AuthChallengeInvalid:
+		return "Sign-in challenge is invalid or expired"
+	case TwoFactorRequired:
+		return "Two-factor code required"
+	case TwoFactorSetupRequired:
+		return "Two-factor authentication setup required"
+	case TwoFactorInvalidCode:
+		return "Invalid two-factor code"
+	case TwoFactorNotEnabled:
+		return "Two-factor authentication is not enabled"
+	case TwoFactorAlreadyEnabled:
+		return "Two-factor authentication is already enabled"
+	case TwoFactorSetupExpired:
+		return "Two-factor setup has expired"
+	case RefreshTokenReused:
+		return "Refresh token reuse detected, session revoked"
+	case SessionContextChanged:
+		return "Session used from a different device or network"
+	case AuthLockedOut:
+		return "Too many failed sign-in attempts. Please try again later."
+	case PasswordChangeRequired:
+		return "Password change required"
+	case TempPasswordExpired:
+		return "Temporary password has expired"
+	case APIKeyInvalid:
+		return "Invalid API key"
+	case APIKeyExpired:
+		return "API key has expired or was revoked"
+	case APIKeyScopeDenied:
+		return "API key is not allowed to access this endpoint"
+	case APIKeyNotFound:
+		return "API key not found"
+	case APIKeyNotAllowed:
+		return "This endpoint does not accept API keys"
+	case ImpersonationForbidden:
+		return "This endpoint is not available while impersonating"
+	case ImpersonationNotAllowed:
+		return "User has not allowed impersonation"
+	case SSOStateInvalid:
+		return "Single sign-on request is unknown or has expired"
+	case SSOLoginFailed:
+		return "Single sign-on failed"
+	case SSOUserNotProvisioned:
+		return "No account is linked to this identity"
+	case SSORoleUnmapped:
+		return "Identity is not assigned to any role"
+
+	// TENANT
+	case TenantRequired:
+		return "Tenant is required"
+	case TenantNotFound:
+		return "Tenant not found"
+	case TenantInactive:
+		return "Tenant is inactive"
+	case SSONotConfigured:
+		return "Single sign-on is not configured"
+	case SSOProviderInvalid:
+		return "Identity provider could not be reached or is misconfigured"
+	case BranchNotFound:
+		return "Branch not found"
+	case BranchAccessDenied:
+		return "No access to the branch"
+	case TenantExists:
+		return "Tenant already exists"
+	case SubscriptionReadOnly:
+		return "Subscription has ended, the tenant is read-only until it is renewed"
+	case SubscriptionExpired:
+		return "Subscription has expired"
+
+	// ACCESS
+	case RoleNotFound:
+		return "Role not found"
+	case RoleExists:
+		return "Role already exists"
+	case RoleProtected:
+		return "Role cannot be changed"
+	case PermissionUnknown:
+		return "Permission is not known"
+	case PermissionDenied:
+		return "Permission denied"
 	default:
 		return "Unknown error"
 	}
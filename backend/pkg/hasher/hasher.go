@@ -1,16 +1,186 @@
 // Package hasher provides functions for hashing and verifying data.
+//
+// Hashes are self-describing: Argon2id hashes use the PHC string format
+// ($argon2id$v=19$m=...,t=...,p=...$salt$hash) and bcrypt hashes keep their
+// own $2a$/$2b$ prefix, so Verify accepts hashes made with any algorithm or
+// parameters, and NeedsRehash tells when one should be upgraded.
 package hasher
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/asliddinberdiev/eirsystem/config"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	AlgArgon2id = "argon2id"
+	AlgBcrypt   = "bcrypt"
+
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+var (
+	ErrMismatch    = errors.New("hash does not match")
+	ErrUnknownHash = errors.New("unknown hash format")
+)
+
+var b64 = base64.RawStdEncoding
+
+// params are the settings new hashes are made with. The defaults follow the
+// second recommended option of RFC 9106 with lower parallelism.
+var params = config.Hasher{
+	Algorithm:         AlgArgon2id,
+	Argon2Memory:      64 * 1024,
+	Argon2Iterations:  3,
+	Argon2Parallelism: 2,
+	BcryptCost:        bcrypt.DefaultCost,
+}
+
+// Init replaces the default settings with the configured ones; zero values
+// keep the defaults. It must be called before the first Hash.
+func Init(cfg config.Hasher) {
+	if cfg.Algorithm == AlgArgon2id || cfg.Algorithm == AlgBcrypt {
+		params.Algorithm = cfg.Algorithm
+	}
+	if cfg.Argon2Memory > 0 {
+		params.Argon2Memory = cfg.Argon2Memory
+	}
+	if cfg.Argon2Iterations > 0 {
+		params.Argon2Iterations = cfg.Argon2Iterations
+	}
+	if cfg.Argon2Parallelism > 0 {
+		params.Argon2Parallelism = cfg.Argon2Parallelism
+	}
+	if cfg.BcryptCost >= bcrypt.MinCost && cfg.BcryptCost <= bcrypt.MaxCost {
+		params.BcryptCost = cfg.BcryptCost
+	}
+}
 
 func Hash(data string) (string, error) {
-	hashedData, err := bcrypt.GenerateFromPassword([]byte(data), bcrypt.DefaultCost)
-	if err != nil {
+	if params.Algorithm == AlgBcrypt {
+		hashedData, err := bcrypt.GenerateFromPassword([]byte(data), params.BcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hashedData), nil
+	}
+
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
 		return "", err
 	}
-	return string(hashedData), nil
+
+	key := argon2.IDKey([]byte(data), salt, params.Argon2Iterations, params.Argon2Memory, params.Argon2Parallelism, argon2KeyLength)
+
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		AlgArgon2id, argon2.Version, params.Argon2Memory, params.Argon2Iterations, params.Argon2Parallelism,
+		b64.EncodeToString(salt), b64.EncodeToString(key),
+	), nil
 }
 
+// Verify returns nil when data matches hashedData, ErrMismatch when it does
+// not and ErrUnknownHash when hashedData is not a hash this package made.
 func Verify(data string, hashedData string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedData), []byte(data))
+	if isBcrypt(hashedData) {
+		err := bcrypt.CompareHashAndPassword([]byte(hashedData), []byte(data))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrMismatch
+		}
+		return err
+	}
+
+	h, err := parseArgon2id(hashedData)
+	if err != nil {
+		return err
+	}
+
+	key := argon2.IDKey([]byte(data), h.salt, h.iterations, h.memory, h.parallelism, uint32(len(h.key)))
+	if subtle.ConstantTimeCompare(key, h.key) != 1 {
+		return ErrMismatch
+	}
+	return nil
+}
+
+// dummyHash is the hash of no real password, made with the configured settings.
+var dummyHash = sync.OnceValue(func() string {
+	hashedData, _ := Hash("dummy password")
+	return hashedData
+})
+
+// VerifyDummy takes as long as verifying data against a real hash and always
+// fails. Sign-in runs it when the username is unknown, so that response times
+// do not tell which usernames exist.
+func VerifyDummy(data string) {
+	_ = Verify(data, dummyHash())
+}
+
+// NeedsRehash reports whether hashedData was made with another algorithm or
+// weaker settings than new hashes are, so that it should be replaced the next
+// time the plain value is known.
+func NeedsRehash(hashedData string) bool {
+	if isBcrypt(hashedData) {
+		if params.Algorithm != AlgBcrypt {
+			return true
+		}
+		cost, err := bcrypt.Cost([]byte(hashedData))
+		return err != nil || cost < params.BcryptCost
+	}
+
+	h, err := parseArgon2id(hashedData)
+	if err != nil {
+		return false
+	}
+	if params.Algorithm != AlgArgon2id {
+		return true
+	}
+	return h.memory < params.Argon2Memory || h.iterations < params.Argon2Iterations || h.parallelism < params.Argon2Parallelism
+}
+
+type argon2idHash struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+func parseArgon2id(hashedData string) (argon2idHash, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(hashedData, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != AlgArgon2id {
+		return argon2idHash{}, ErrUnknownHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return argon2idHash{}, ErrUnknownHash
+	}
+
+	var h argon2idHash
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &h.memory, &h.iterations, &h.parallelism); err != nil {
+		return argon2idHash{}, ErrUnknownHash
+	}
+
+	var err error
+	if h.salt, err = b64.DecodeString(parts[4]); err != nil {
+		return argon2idHash{}, ErrUnknownHash
+	}
+	if h.key, err = b64.DecodeString(parts[5]); err != nil || len(h.key) == 0 {
+		return argon2idHash{}, ErrUnknownHash
+	}
+
+	return h, nil
+}
+
+func isBcrypt(hashedData string) bool {
+	return strings.HasPrefix(hashedData, "$2a$") || strings.HasPrefix(hashedData, "$2b$") || strings.HasPrefix(hashedData, "$2y$")
 }
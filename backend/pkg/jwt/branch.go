@@ -0,0 +1,50 @@
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// SetSessionBranch makes branchID the active branch of the session; "" clears
+// it. Every access token of the session sees the change at once.
+func (m *Manager) SetSessionBranch(ctx context.Context, userID, sessionID, branchID string) error {
+	key := m.getSessionKey(userID, sessionID)
+
+	var err error
+	for range refreshTries {
+		err = m.rdb.Watch(ctx, func(tx *redis.Tx) error {
+			val, err := tx.Get(ctx, key).Result()
+			if err == redis.Nil {
+				return ErrSessionNotFound
+			}
+			if err != nil {
+				return fmt.Errorf("redis error: %w", err)
+			}
+
+			var sessionData SessionData
+			if err := json.Unmarshal([]byte(val), &sessionData); err != nil {
+				return fmt.Errorf("json unmarshal error: %w", err)
+			}
+			sessionData.BranchID = branchID
+
+			jsonData, err := json.Marshal(sessionData)
+			if err != nil {
+				return fmt.Errorf("json marshal error: %w", err)
+			}
+
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.Set(ctx, key, jsonData, redis.KeepTTL)
+				return nil
+			})
+			return err
+		}, key)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return err
+}
@@ -0,0 +1,89 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/asliddinberdiev/eirsystem/pkg/codes"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	ChallengeTwoFactor       = "2fa"
+	ChallengeTwoFactorEnroll = "2fa_enroll"
+
+	ChallengeTTL = 5 * time.Minute
+
+	challengeAudience    = "eirsystem:challenge"
+	maxChallengeAttempts = 5
+)
+
+var ErrChallengeInvalid = errors.New(codes.AuthChallengeInvalid.String())
+
+// ChallengeClaims identify a user who passed the password step of sign-in
+// but still has to complete the step named by Purpose.
+type ChallengeClaims struct {
+	jwt.RegisteredClaims
+	UserID  string `json:"sub"`
+	Purpose string `json:"purpose"`
+}
+
+func (m *Manager) GenerateChallenge(ctx context.Context, userID, purpose string) (string, error) {
+	claims := ChallengeClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "eirsystem",
+			Subject:   userID,
+			Audience:  jwt.ClaimStrings{challengeAudience},
+			ID:        uuid.New().String(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ChallengeTTL)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+		UserID:  userID,
+		Purpose: purpose,
+	}
+
+	return m.sign(ctx, claims)
+}
+
+// ValidateChallenge verifies the challenge token and counts an attempt
+// against it, so a stolen token cannot be used to brute-force codes.
+func (m *Manager) ValidateChallenge(ctx context.Context, tokenStr, purpose string) (*ChallengeClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenStr, &ChallengeClaims{}, m.keyFunc(ctx),
+		jwt.WithValidMethods([]string{AlgRS256, AlgEdDSA}),
+		jwt.WithAudience(challengeAudience),
+	)
+	if err != nil {
+		return nil, ErrChallengeInvalid
+	}
+
+	claims, ok := token.Claims.(*ChallengeClaims)
+	if !ok || !token.Valid || claims.Purpose != purpose {
+		return nil, ErrChallengeInvalid
+	}
+
+	key := m.getChallengeKey(claims.ID)
+	attempts, err := m.rdb.Incr(ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis incr error: %w", err)
+	}
+	if attempts == 1 {
+		m.rdb.Expire(ctx, key, ChallengeTTL)
+	}
+	if attempts > maxChallengeAttempts {
+		return nil, ErrChallengeInvalid
+	}
+
+	return claims, nil
+}
+
+// ConsumeChallenge makes the challenge unusable once it has been completed.
+func (m *Manager) ConsumeChallenge(ctx context.Context, claims *ChallengeClaims) error {
+	return m.rdb.Set(ctx, m.getChallengeKey(claims.ID), maxChallengeAttempts, ChallengeTTL).Err()
+}
+
+func (m *Manager) getChallengeKey(id string) string {
+	return "auth:challenge:" + id
+}
@@ -0,0 +1,73 @@
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const defaultImpersonationTTL = 15 * time.Minute
+
+// Impersonation is a short-lived session opened by impersonatorID as another user.
+type Impersonation struct {
+	AccessToken string
+	SessionID   string
+	ExpiresAt   time.Time
+}
+
+// Impersonate opens a session of userID on behalf of impersonatorID. The access
+// token carries the impersonator in its "act" claim and cannot be refreshed, so
+// the session ends when the token expires.
+func (m *Manager) Impersonate(ctx context.Context, userID, role, tenantID, impersonatorID, userAgent, clientIP string) (Impersonation, error) {
+	ttl := m.impersonationTTL()
+
+	now := time.Now()
+	sessionID := uuid.New().String()
+	expiresAt := now.Add(ttl)
+
+	claims := newAccessClaims(userID, sessionID, role, tenantID, expiresAt)
+	claims.Actor = &Actor{Subject: impersonatorID}
+
+	accessToken, err := m.sign(ctx, claims)
+	if err != nil {
+		return Impersonation{}, err
+	}
+
+	jsonData, err := json.Marshal(SessionData{
+		Role:           role,
+		TenantID:       tenantID,
+		UserAgent:      userAgent,
+		ClientIP:       clientIP,
+		CreatedAt:      now.Unix(),
+		ExpiresAt:      expiresAt.Unix(),
+		ImpersonatorID: impersonatorID,
+	})
+	if err != nil {
+		return Impersonation{}, fmt.Errorf("json marshal error: %w", err)
+	}
+
+	_, err = m.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		m.saveSession(ctx, pipe, userID, sessionID, jsonData, expiresAt.Unix())
+		return nil
+	})
+	if err != nil {
+		return Impersonation{}, fmt.Errorf("redis set error: %w", err)
+	}
+
+	return Impersonation{
+		AccessToken: accessToken,
+		SessionID:   sessionID,
+		ExpiresAt:   expiresAt,
+	}, nil
+}
+
+func (m *Manager) impersonationTTL() time.Duration {
+	if m.cfg.ImpersonationTTL <= 0 {
+		return defaultImpersonationTTL
+	}
+	return m.cfg.ImpersonationTTL
+}
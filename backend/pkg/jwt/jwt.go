@@ -3,9 +3,12 @@ package jwt
 
 import (
 	"context"
+	"crypto/cipher"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/asliddinberdiev/eirsystem/config"
@@ -15,9 +18,25 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+var (
+	ErrTokenExpired    = errors.New(codes.AuthTokenExpired.String())
+	ErrTokenInvalid    = errors.New(codes.AuthTokenInvalid.String())
+	ErrUserBlocked     = errors.New(codes.UserBlocked.String())
+	ErrSessionRevoked  = errors.New(codes.SessionRevoked.String())
+	ErrSessionMismatch = errors.New(codes.SessionMismatch.String())
+	ErrSessionNotFound = errors.New(codes.SessionNotFound.String())
+	ErrRefreshReused   = errors.New(codes.RefreshTokenReused.String())
+	ErrSessionDrift    = errors.New(codes.SessionContextChanged.String())
+)
+
+const sessionIndexMarkerKey = "jwt:sessions:indexed"
+
 type Manager struct {
-	cfg *config.JWT
-	rdb *redis.Client
+	cfg     *config.JWT
+	rdb     *redis.Client
+	kek     cipher.AEAD
+	ring    keyRing
+	onEvent func(context.Context, SecurityEvent)
 }
 
 type CustomClaims struct {
@@ -27,34 +46,77 @@ type CustomClaims struct {
 	Role      string `json:"role,omitempty"`
 	CompanyID string `json:"company_id,omitempty"`
 	TenantID  string `json:"tenant_id,omitempty"`
+	Actor     *Actor `json:"act,omitempty"`
+
+	// BranchID is the active branch of the session. It is not part of the
+	// token; ValidateAccessToken reads it from the session.
+	BranchID string `json:"-"`
+}
+
+// Actor is the party acting on behalf of the subject (RFC 8693 "act" claim),
+// set only on impersonation tokens.
+type Actor struct {
+	Subject string `json:"sub"`
+}
+
+// ImpersonatorID returns the user impersonating the subject, or "" for a normal token.
+func (c *CustomClaims) ImpersonatorID() string {
+	if c.Actor == nil {
+		return ""
+	}
+	return c.Actor.Subject
 }
 
 type SessionData struct {
-	RefreshToken string `json:"rt"`
-	UserAgent    string `json:"ua"`
-	ClientIP     string `json:"ip"`
-	CreatedAt    int64  `json:"created_at"`
-	ExpiresAt    int64  `json:"expires_at"`
+	RefreshToken   string   `json:"rt"`
+	Role           string   `json:"role"`
+	TenantID       string   `json:"tenant_id"`
+	UserAgent      string   `json:"ua"`
+	ClientIP       string   `json:"ip"`
+	CreatedAt      int64    `json:"created_at"`
+	ExpiresAt      int64    `json:"expires_at"`
+	UsedTokens     []string `json:"used,omitempty"`
+	RotatedAt      int64    `json:"rotated_at,omitempty"`
+	ImpersonatorID string   `json:"impersonator_id,omitempty"`
+	BranchID       string   `json:"branch_id,omitempty"`
+}
+
+type Session struct {
+	ID             string
+	UserAgent      string
+	ClientIP       string
+	CreatedAt      int64
+	ExpiresAt      int64
+	IsCurrent      bool
+	ImpersonatorID string
 }
 
-func New(cfg *config.JWT, rdb *redis.Client) *Manager {
+func New(cfg *config.JWT, rdb *redis.Client) (*Manager, error) {
+	kek, err := newKeyCipher(cfg.KeyEncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Manager{
 		cfg: cfg,
 		rdb: rdb,
-	}
+		kek: kek,
+	}, nil
 }
 
-func (m *Manager) Generate(ctx context.Context, userID, userAgent, clientIP string) (accessToken, refreshToken string, err error) {
+func (m *Manager) Generate(ctx context.Context, userID, role, tenantID, userAgent, clientIP string) (accessToken, refreshToken string, err error) {
 	sessionID := uuid.New().String()
 	refreshToken = uuid.New().String()
 
-	accessToken, err = m.generateAccessToken(userID, sessionID)
+	accessToken, err = m.generateAccessToken(ctx, userID, sessionID, role, tenantID)
 	if err != nil {
 		return "", "", err
 	}
 
 	sessionData := SessionData{
 		RefreshToken: refreshToken,
+		Role:         role,
+		TenantID:     tenantID,
 		UserAgent:    userAgent,
 		ClientIP:     clientIP,
 		CreatedAt:    time.Now().Unix(),
@@ -66,8 +128,10 @@ func (m *Manager) Generate(ctx context.Context, userID, userAgent, clientIP stri
 		return "", "", fmt.Errorf("json marshal error: %w", err)
 	}
 
-	key := m.getSessionKey(userID, sessionID)
-	err = m.rdb.Set(ctx, key, jsonData, m.cfg.RefreshExpireMinutes).Err()
+	_, err = m.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		m.saveSession(ctx, pipe, userID, sessionID, jsonData, sessionData.ExpiresAt)
+		return nil
+	})
 	if err != nil {
 		return "", "", fmt.Errorf("redis set error: %w", err)
 	}
@@ -76,22 +140,17 @@ func (m *Manager) Generate(ctx context.Context, userID, userAgent, clientIP stri
 }
 
 func (m *Manager) ValidateAccessToken(ctx context.Context, tokenStr string) (*CustomClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenStr, &CustomClaims{}, func(token *jwt.Token) (any, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(m.cfg.SecretKey), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenStr, &CustomClaims{}, m.keyFunc(ctx), jwt.WithValidMethods([]string{AlgRS256, AlgEdDSA}))
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
-			return nil, errors.New(codes.AuthTokenExpired.String())
+			return nil, ErrTokenExpired
 		}
-		return nil, errors.New(codes.AuthTokenInvalid.String())
+		return nil, ErrTokenInvalid
 	}
 
 	claims, ok := token.Claims.(*CustomClaims)
 	if !ok || !token.Valid {
-		return nil, errors.New(codes.AuthTokenInvalid.String())
+		return nil, ErrTokenInvalid
 	}
 
 	isBlocked, err := m.rdb.Exists(ctx, m.getBlockKey(claims.UserID)).Result()
@@ -99,129 +158,234 @@ func (m *Manager) ValidateAccessToken(ctx context.Context, tokenStr string) (*Cu
 		return nil, fmt.Errorf("redis check error: %w", err)
 	}
 	if isBlocked > 0 {
-		return nil, errors.New(codes.UserBlocked.String())
+		return nil, ErrUserBlocked
 	}
 
-	exists, err := m.rdb.Exists(ctx, m.getSessionKey(claims.UserID, claims.SessionID)).Result()
-	if err != nil || exists == 0 {
-		return nil, errors.New(codes.SessionRevoked.String())
-	}
-
-	return claims, nil
-}
-
-func (m *Manager) Refresh(ctx context.Context, userID, sessionID, oldRefreshToken, currentUserAgent string) (newAccess, newRefresh string, err error) {
-	key := m.getSessionKey(userID, sessionID)
-
-	val, err := m.rdb.Get(ctx, key).Result()
-	if err == redis.Nil {
-		return "", "", errors.New(codes.SessionRevoked.String())
-	}
+	val, err := m.rdb.Get(ctx, m.getSessionKey(claims.UserID, claims.SessionID)).Result()
 	if err != nil {
-		return "", "", fmt.Errorf("redis error: %w", err)
+		return nil, ErrSessionRevoked
 	}
 
 	var sessionData SessionData
 	if err := json.Unmarshal([]byte(val), &sessionData); err != nil {
-		return "", "", fmt.Errorf("json unmarshal error: %w", err)
+		return nil, ErrSessionRevoked
 	}
+	claims.BranchID = sessionData.BranchID
 
-	if sessionData.RefreshToken != oldRefreshToken {
-		m.rdb.Del(ctx, key)
-		return "", "", errors.New(codes.SessionMismatch.String())
-	}
+	return claims, nil
+}
 
-	if sessionData.UserAgent != currentUserAgent {
-		return "", "", errors.New(codes.SessionRevoked.String())
-	}
+func (m *Manager) Logout(ctx context.Context, userID, sessionID string) error {
+	_, err := m.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		m.removeSession(ctx, pipe, userID, sessionID)
+		return nil
+	})
+	return err
+}
 
-	newRefresh = uuid.New().String()
-	newAccess, err = m.generateAccessToken(userID, sessionID)
+func (m *Manager) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	var deleted *redis.IntCmd
+	_, err := m.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		deleted = m.removeSession(ctx, pipe, userID, sessionID)
+		return nil
+	})
 	if err != nil {
-		return "", "", err
+		return fmt.Errorf("redis delete error: %w", err)
 	}
+	if deleted.Val() == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
 
-	sessionData.RefreshToken = newRefresh
+func (m *Manager) LogoutAll(ctx context.Context, userID string) error {
+	return m.deleteSessions(ctx, userID, "")
+}
+
+// LogoutOthers revokes every session of the user except keepSessionID.
+func (m *Manager) LogoutOthers(ctx context.Context, userID, keepSessionID string) error {
+	return m.deleteSessions(ctx, userID, keepSessionID)
+}
 
-	newJSONData, err := json.Marshal(sessionData)
+func (m *Manager) deleteSessions(ctx context.Context, userID, keepSessionID string) error {
+	sessionIDs, err := m.rdb.ZRange(ctx, m.getSessionIndexKey(userID), 0, -1).Result()
 	if err != nil {
-		return "", "", err
+		return fmt.Errorf("redis range error: %w", err)
 	}
 
-	err = m.rdb.Set(ctx, key, newJSONData, m.cfg.RefreshExpireMinutes).Err()
+	_, err = m.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, sessionID := range sessionIDs {
+			if sessionID != keepSessionID {
+				m.removeSession(ctx, pipe, userID, sessionID)
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		return "", "", fmt.Errorf("redis update error: %w", err)
+		return fmt.Errorf("redis delete error: %w", err)
 	}
+	return nil
+}
 
-	return newAccess, newRefresh, nil
+// Block marks the user as blocked for ttl; a zero ttl blocks until Unblock is called.
+func (m *Manager) Block(ctx context.Context, userID string, ttl time.Duration) error {
+	return m.rdb.Set(ctx, m.getBlockKey(userID), "1", ttl).Err()
 }
 
-func (m *Manager) Logout(ctx context.Context, userID, sessionID string) error {
-	return m.rdb.Del(ctx, m.getSessionKey(userID, sessionID)).Err()
+func (m *Manager) Unblock(ctx context.Context, userID string) error {
+	return m.rdb.Del(ctx, m.getBlockKey(userID)).Err()
 }
 
-func (m *Manager) LogoutAll(ctx context.Context, userID string) error {
-	pattern := fmt.Sprintf("user:%s:session:*", userID)
+// GetUserSessions lists the live sessions of the user, newest expiry first.
+// Index entries whose session has expired or disappeared are pruned on the way.
+func (m *Manager) GetUserSessions(ctx context.Context, userID, currentSessionID string) ([]Session, error) {
+	indexKey := m.getSessionIndexKey(userID)
 
-	iter := m.rdb.Scan(ctx, 0, pattern, 0).Iterator()
-	for iter.Next(ctx) {
-		if err := m.rdb.Del(ctx, iter.Val()).Err(); err != nil {
-			fmt.Printf("failed to delete session key %s: %v\n", iter.Val(), err)
-		}
+	if err := m.pruneSessionIndex(ctx, userID); err != nil {
+		return nil, err
 	}
-	if err := iter.Err(); err != nil {
-		return err
+
+	sessionIDs, err := m.rdb.ZRevRange(ctx, indexKey, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis range error: %w", err)
+	}
+	if len(sessionIDs) == 0 {
+		return []Session{}, nil
 	}
-	return nil
-}
 
-func (m *Manager) GetUserSessions(ctx context.Context, userID string) ([]map[string]interface{}, error) {
-	pattern := fmt.Sprintf("user:%s:session:*", userID)
-	var sessions []map[string]interface{}
+	keys := make([]string, len(sessionIDs))
+	for i, sessionID := range sessionIDs {
+		keys[i] = m.getSessionKey(userID, sessionID)
+	}
 
-	iter := m.rdb.Scan(ctx, 0, pattern, 0).Iterator()
-	for iter.Next(ctx) {
-		key := iter.Val()
-		val, err := m.rdb.Get(ctx, key).Result()
-		if err == nil {
-			var s SessionData
-			if json.Unmarshal([]byte(val), &s) == nil {
-				sid := key[len(key)-36:]
-
-				sessions = append(sessions, map[string]interface{}{
-					"session_id": sid,
-					"user_agent": s.UserAgent,
-					"client_ip":  s.ClientIP,
-					"created_at": s.CreatedAt,
-					"is_current": false, 
-				})
-			}
+	values, err := m.rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis mget error: %w", err)
+	}
+
+	sessions := make([]Session, 0, len(sessionIDs))
+	var stale []any
+	for i, value := range values {
+		raw, ok := value.(string)
+		var s SessionData
+		if !ok || json.Unmarshal([]byte(raw), &s) != nil {
+			stale = append(stale, sessionIDs[i])
+			continue
+		}
+
+		sessions = append(sessions, Session{
+			ID:             sessionIDs[i],
+			UserAgent:      s.UserAgent,
+			ClientIP:       s.ClientIP,
+			CreatedAt:      s.CreatedAt,
+			ExpiresAt:      s.ExpiresAt,
+			IsCurrent:      sessionIDs[i] == currentSessionID,
+			ImpersonatorID: s.ImpersonatorID,
+		})
+	}
+
+	if len(stale) > 0 {
+		if err := m.rdb.ZRem(ctx, indexKey, stale...).Err(); err != nil {
+			return nil, fmt.Errorf("redis zrem error: %w", err)
 		}
 	}
+
 	return sessions, nil
 }
 
-func (m *Manager) generateAccessToken(userID, sessionID string) (string, error) {
-	claims := CustomClaims{
+func (m *Manager) generateAccessToken(ctx context.Context, userID, sessionID, role, tenantID string) (string, error) {
+	return m.sign(ctx, newAccessClaims(userID, sessionID, role, tenantID, time.Now().Add(m.cfg.AccessExpireMinutes)))
+}
+
+func newAccessClaims(userID, sessionID, role, tenantID string, expiresAt time.Time) CustomClaims {
+	return CustomClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    "eirsystem",
 			Subject:   userID,
 			ID:        uuid.New().String(),
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(m.cfg.AccessExpireMinutes)),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 		UserID:    userID,
 		SessionID: sessionID,
+		Role:      role,
+		TenantID:  tenantID,
+	}
+}
+
+// IndexLegacySessions adds sessions created before the per-user index existed
+// to it, so LogoutAll still reaches them. It walks the keyspace once; later
+// calls return at once.
+func (m *Manager) IndexLegacySessions(ctx context.Context) error {
+	first, err := m.rdb.SetNX(ctx, sessionIndexMarkerKey, 1, 0).Result()
+	if err != nil || !first {
+		return err
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(m.cfg.SecretKey))
+	iter := m.rdb.Scan(ctx, 0, "user:*:session:*", 1000).Iterator()
+	for iter.Next(ctx) {
+		parts := strings.Split(iter.Val(), ":")
+		if len(parts) != 4 {
+			continue
+		}
+		userID, sessionID := parts[1], parts[3]
+
+		ttl, err := m.rdb.TTL(ctx, iter.Val()).Result()
+		if err != nil || ttl <= 0 {
+			continue
+		}
+
+		indexKey := m.getSessionIndexKey(userID)
+		_, err = m.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.ZAdd(ctx, indexKey, redis.Z{Score: float64(time.Now().Add(ttl).Unix()), Member: sessionID})
+			pipe.Expire(ctx, indexKey, m.cfg.RefreshExpireMinutes)
+			return nil
+		})
+		if err != nil {
+			m.rdb.Del(ctx, sessionIndexMarkerKey)
+			return fmt.Errorf("redis index error: %w", err)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		m.rdb.Del(ctx, sessionIndexMarkerKey)
+		return err
+	}
+	return nil
+}
+
+// saveSession stores the session and indexes it under the user, scored by its
+// expiry. No session lives longer than the refresh lifetime, so the index can
+// simply expire that long after the latest save.
+func (m *Manager) saveSession(ctx context.Context, pipe redis.Pipeliner, userID, sessionID string, data []byte, expiresAt int64) {
+	indexKey := m.getSessionIndexKey(userID)
+
+	pipe.Set(ctx, m.getSessionKey(userID, sessionID), data, time.Until(time.Unix(expiresAt, 0)))
+	pipe.ZAdd(ctx, indexKey, redis.Z{Score: float64(expiresAt), Member: sessionID})
+	pipe.Expire(ctx, indexKey, m.cfg.RefreshExpireMinutes)
+}
+
+func (m *Manager) removeSession(ctx context.Context, pipe redis.Pipeliner, userID, sessionID string) *redis.IntCmd {
+	deleted := pipe.Del(ctx, m.getSessionKey(userID, sessionID))
+	pipe.ZRem(ctx, m.getSessionIndexKey(userID), sessionID)
+	return deleted
+}
+
+func (m *Manager) pruneSessionIndex(ctx context.Context, userID string) error {
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	if err := m.rdb.ZRemRangeByScore(ctx, m.getSessionIndexKey(userID), "-inf", "("+now).Err(); err != nil {
+		return fmt.Errorf("redis prune error: %w", err)
+	}
+	return nil
 }
 
 func (m *Manager) getSessionKey(userID, sessionID string) string {
 	return fmt.Sprintf("user:%s:session:%s", userID, sessionID)
 }
 
+func (m *Manager) getSessionIndexKey(userID string) string {
+	return fmt.Sprintf("user:%s:sessions", userID)
+}
+
 func (m *Manager) getBlockKey(userID string) string {
 	return "user:blocked:" + userID
 }
@@ -238,4 +402,4 @@ func (m *Manager) ParseTokenUnverified(tokenStr string) (*CustomClaims, error) {
 	}
 
 	return claims, nil
-}
\ No newline at end of file
+}
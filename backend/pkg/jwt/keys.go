@@ -0,0 +1,459 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+
+	defaultAlgorithm      = AlgEdDSA
+	defaultRotationPeriod = 30 * 24 * time.Hour
+
+	keysHashKey = "jwt:keys"
+	keysLockKey = "jwt:keys:lock"
+
+	// keyCacheTTL bounds how long an instance signs with a key list that
+	// another instance may already have rotated.
+	keyCacheTTL = 30 * time.Second
+	// keyPublishLead is how long a new key sits in the JWKS before it signs,
+	// so that verifiers caching the JWKS already know it on first use.
+	keyPublishLead = 10 * time.Minute
+	keyLockTTL     = 10 * time.Second
+	rsaKeyBits     = 2048
+
+	// keyReloadInterval is how often a token with an unknown kid may make an
+	// instance reload the keys, so forged kids cannot drive a Redis read each.
+	keyReloadInterval = 5 * time.Second
+)
+
+var errUnknownKey = errors.New("unknown signing key")
+
+// signingKey is a key pair known to every instance. It signs between ActiveAt
+// and RotateAt, and only verifies from RotateAt until ExpiresAt, when every
+// token it signed, access, impersonation or challenge, has expired.
+type signingKey struct {
+	ID        string
+	Algorithm string
+	Private   crypto.Signer
+	ActiveAt  time.Time
+	RotateAt  time.Time
+	ExpiresAt time.Time
+}
+
+func (k *signingKey) signs(now time.Time) bool {
+	return !now.Before(k.ActiveAt) && now.Before(k.RotateAt)
+}
+
+// storedKey is the Redis representation of a signingKey. The private key is
+// PKCS8 sealed with the key-encryption key, so Redis alone cannot mint tokens.
+type storedKey struct {
+	ID        string `json:"kid"`
+	Algorithm string `json:"alg"`
+	SealedKey string `json:"sealed_key"`
+	ActiveAt  int64  `json:"active_at"`
+	RotateAt  int64  `json:"rotate_at"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+type keyRing struct {
+	mu       sync.RWMutex
+	keys     map[string]*signingKey
+	loadedAt time.Time
+	forcedAt time.Time
+}
+
+// claimReload reports whether the keys may be reloaded for an unknown kid.
+// Concurrent callers get one reload per keyReloadInterval between them.
+func (r *keyRing) claimReload(now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now.Sub(r.loadedAt) < keyReloadInterval || now.Sub(r.forcedAt) < keyReloadInterval {
+		return false
+	}
+	r.forcedAt = now
+	return true
+}
+
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public part of every key that can still verify tokens,
+// including the upcoming key that has not started signing yet.
+func (m *Manager) JWKS(ctx context.Context) (JWKSet, error) {
+	keys, err := m.loadKeys(ctx, false)
+	if err != nil {
+		return JWKSet{}, err
+	}
+
+	set := JWKSet{Keys: []JWK{}}
+	for _, k := range keys {
+		jwk := JWK{KeyID: k.ID, Use: "sig", Algorithm: k.Algorithm}
+
+		switch pub := k.Private.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set, nil
+}
+
+func (m *Manager) sign(ctx context.Context, claims jwt.Claims) (string, error) {
+	key, err := m.currentKey(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Private)
+}
+
+func (m *Manager) keyFunc(ctx context.Context) jwt.Keyfunc {
+	return func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, errUnknownKey
+		}
+
+		key, err := m.verificationKey(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+
+		if token.Method.Alg() != key.Algorithm {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+
+		return key.Private.Public(), nil
+	}
+}
+
+// currentKey returns the key that signs right now, creating one when none is
+// active and scheduling its successor once rotation is near.
+func (m *Manager) currentKey(ctx context.Context) (*signingKey, error) {
+	now := time.Now()
+
+	keys, err := m.loadKeys(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	current, next := pickKeys(keys, m.algorithm(), now)
+	if current == nil {
+		for range 20 {
+			created, err := m.createKey(ctx, now)
+			if err != nil {
+				return nil, err
+			}
+			if created {
+				break
+			}
+			// Another instance holds the lock and is creating the key.
+			time.Sleep(100 * time.Millisecond)
+			if keys, err = m.loadKeys(ctx, true); err != nil {
+				return nil, err
+			}
+			if current, _ = pickKeys(keys, m.algorithm(), now); current != nil {
+				return current, nil
+			}
+		}
+
+		if keys, err = m.loadKeys(ctx, true); err != nil {
+			return nil, err
+		}
+		if current, next = pickKeys(keys, m.algorithm(), now); current == nil {
+			return nil, errors.New("no active signing key")
+		}
+	}
+
+	if next == nil && current.RotateAt.Sub(now) < keyPublishLead {
+		if _, err := m.createKey(ctx, current.RotateAt); err != nil {
+			return nil, err
+		}
+	}
+
+	return current, nil
+}
+
+// verificationKey returns the key with the kid. A kid missing from the cached
+// keys reloads them, at most once per keyReloadInterval; new keys are
+// published long before they sign, so a recent copy only misses forged kids.
+func (m *Manager) verificationKey(ctx context.Context, kid string) (*signingKey, error) {
+	for _, force := range []bool{false, true} {
+		if force && !m.ring.claimReload(time.Now()) {
+			break
+		}
+
+		keys, err := m.loadKeys(ctx, force)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range keys {
+			if k.ID == kid {
+				return k, nil
+			}
+		}
+	}
+	return nil, errUnknownKey
+}
+
+// loadKeys returns the unexpired keys sorted by activation time, reading
+// Redis only when the in-memory copy is stale or force is set.
+func (m *Manager) loadKeys(ctx context.Context, force bool) ([]*signingKey, error) {
+	m.ring.mu.RLock()
+	if !force && m.ring.keys != nil && time.Since(m.ring.loadedAt) < keyCacheTTL {
+		keys := sortedKeys(m.ring.keys)
+		m.ring.mu.RUnlock()
+		return keys, nil
+	}
+	m.ring.mu.RUnlock()
+
+	raw, err := m.rdb.HGetAll(ctx, keysHashKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis keys load error: %w", err)
+	}
+
+	now := time.Now()
+	loaded := make(map[string]*signingKey, len(raw))
+	for kid, val := range raw {
+		var stored storedKey
+		err := json.Unmarshal([]byte(val), &stored)
+		// Keys stored before they were sealed are dropped along with expired ones.
+		if err != nil || stored.SealedKey == "" || !now.Before(time.Unix(stored.ExpiresAt, 0)) {
+			m.rdb.HDel(ctx, keysHashKey, kid)
+			continue
+		}
+
+		key, err := m.openKey(stored)
+		if err != nil {
+			// Sealed with another key-encryption key; left to the instances
+			// configured with it.
+			continue
+		}
+		loaded[kid] = key
+	}
+
+	m.ring.mu.Lock()
+	m.ring.keys = loaded
+	m.ring.loadedAt = now
+	m.ring.mu.Unlock()
+
+	return sortedKeys(loaded), nil
+}
+
+// createKey generates a key that starts signing at activeAt. It reports false
+// without error when another instance is already creating one.
+func (m *Manager) createKey(ctx context.Context, activeAt time.Time) (bool, error) {
+	locked, err := m.rdb.SetNX(ctx, keysLockKey, "1", keyLockTTL).Result()
+	if err != nil {
+		return false, fmt.Errorf("redis lock error: %w", err)
+	}
+	if !locked {
+		return false, nil
+	}
+	defer m.rdb.Del(ctx, keysLockKey)
+
+	private, err := generatePrivateKey(m.algorithm())
+	if err != nil {
+		return false, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return false, fmt.Errorf("private key marshal error: %w", err)
+	}
+
+	rotateAt := activeAt.Add(m.rotationPeriod())
+	stored := storedKey{
+		ID:        uuid.New().String(),
+		Algorithm: m.algorithm(),
+		ActiveAt:  activeAt.Unix(),
+		RotateAt:  rotateAt.Unix(),
+		ExpiresAt: rotateAt.Add(m.maxTokenTTL() + time.Minute).Unix(),
+	}
+
+	stored.SealedKey, err = m.sealKey(stored.ID, der)
+	if err != nil {
+		return false, err
+	}
+
+	jsonData, err := json.Marshal(stored)
+	if err != nil {
+		return false, fmt.Errorf("json marshal error: %w", err)
+	}
+
+	if err := m.rdb.HSet(ctx, keysHashKey, stored.ID, jsonData).Err(); err != nil {
+		return false, fmt.Errorf("redis keys save error: %w", err)
+	}
+
+	if _, err := m.loadKeys(ctx, true); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// maxTokenTTL is the longest a token signed with a key can live, which is how
+// long the key has to verify tokens after it stops signing.
+func (m *Manager) maxTokenTTL() time.Duration {
+	return max(m.cfg.AccessExpireMinutes, m.impersonationTTL(), ChallengeTTL)
+}
+
+func (m *Manager) algorithm() string {
+	if m.cfg.Algorithm == "" {
+		return defaultAlgorithm
+	}
+	return m.cfg.Algorithm
+}
+
+func (m *Manager) rotationPeriod() time.Duration {
+	if m.cfg.KeyRotationInterval <= 0 {
+		return defaultRotationPeriod
+	}
+	return m.cfg.KeyRotationInterval
+}
+
+// pickKeys returns the newest key of alg that signs at now, and a key of alg
+// that is scheduled to start signing later.
+func pickKeys(keys []*signingKey, alg string, now time.Time) (current, next *signingKey) {
+	for _, k := range keys {
+		if k.Algorithm != alg {
+			continue
+		}
+		if k.signs(now) {
+			current = k
+		} else if k.ActiveAt.After(now) {
+			next = k
+		}
+	}
+	return current, next
+}
+
+func sortedKeys(keys map[string]*signingKey) []*signingKey {
+	sorted := make([]*signingKey, 0, len(keys))
+	for _, k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].ActiveAt.Before(sorted[j].ActiveAt)
+	})
+	return sorted
+}
+
+func generatePrivateKey(alg string) (crypto.Signer, error) {
+	switch alg {
+	case AlgRS256:
+		return rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case AlgEdDSA:
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		return private, err
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", alg)
+	}
+}
+
+// newKeyCipher parses the base64 key-encryption key, which must hold 32 bytes
+// for AES-256-GCM.
+func newKeyCipher(encoded string) (cipher.AEAD, error) {
+	kek, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(kek) != 32 {
+		return nil, errors.New("jwt key_encryption_key must be 32 bytes encoded in base64")
+	}
+
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealKey encrypts the PKCS8 private key, bound to its kid so that a sealed key
+// cannot be swapped under another id.
+func (m *Manager) sealKey(kid string, der []byte) (string, error) {
+	nonce := make([]byte, m.kek.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := m.kek.Seal(nonce, nonce, der, []byte(kid))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (m *Manager) openKey(stored storedKey) (*signingKey, error) {
+	sealed, err := base64.StdEncoding.DecodeString(stored.SealedKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < m.kek.NonceSize() {
+		return nil, errors.New("sealed key is too short")
+	}
+
+	nonce, ciphertext := sealed[:m.kek.NonceSize()], sealed[m.kek.NonceSize():]
+	der, err := m.kek.Open(nil, nonce, ciphertext, []byte(stored.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+
+	private, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("private key is not a signer")
+	}
+
+	return &signingKey{
+		ID:        stored.ID,
+		Algorithm: stored.Algorithm,
+		Private:   private,
+		ActiveAt:  time.Unix(stored.ActiveAt, 0),
+		RotateAt:  time.Unix(stored.RotateAt, 0),
+		ExpiresAt: time.Unix(stored.ExpiresAt, 0),
+	}, nil
+}
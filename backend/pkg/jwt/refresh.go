@@ -0,0 +1,246 @@
+package jwt
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Drift policies decide what happens when a session is refreshed from a
+// different User-Agent or IP than the one it was last used from.
+const (
+	DriftIgnore = "ignore"
+	DriftAlert  = "alert"
+	DriftReject = "reject"
+	DriftRevoke = "revoke"
+)
+
+const (
+	EventRefreshReuse   = "refresh_token_reuse"
+	EventUserAgentDrift = "user_agent_drift"
+	EventIPDrift        = "ip_drift"
+
+	ActionAllowed  = "allowed"
+	ActionRejected = "rejected"
+	ActionRevoked  = "revoked"
+
+	// maxUsedTokens bounds how many rotated refresh tokens of a session
+	// family are remembered for reuse detection.
+	maxUsedTokens = 50
+	// reuseGrace tolerates two tabs refreshing with the same token at once:
+	// the token rotated just before is rejected without revoking the family.
+	reuseGrace   = 10 * time.Second
+	refreshTries = 3
+)
+
+var versionPattern = regexp.MustCompile(`[0-9][0-9._]*`)
+
+// SecurityEvent describes a suspicious refresh of a session family.
+type SecurityEvent struct {
+	Type              string
+	Action            string
+	UserID            string
+	TenantID          string
+	SessionID         string
+	UserAgent         string
+	ClientIP          string
+	PreviousUserAgent string
+	PreviousClientIP  string
+}
+
+// OnSecurityEvent registers the handler that records and alerts on
+// suspected token theft. It must be set before the manager is used.
+func (m *Manager) OnSecurityEvent(fn func(context.Context, SecurityEvent)) {
+	m.onEvent = fn
+}
+
+// Refresh rotates the refresh token of a session family. Presenting a token
+// that was already rotated out means it leaked, so the whole family is revoked.
+func (m *Manager) Refresh(ctx context.Context, userID, sessionID, oldRefreshToken, currentUserAgent, clientIP string) (newAccess, newRefresh string, err error) {
+	key := m.getSessionKey(userID, sessionID)
+
+	var events []SecurityEvent
+	for range refreshTries {
+		events = nil
+		err = m.rdb.Watch(ctx, func(tx *redis.Tx) error {
+			val, err := tx.Get(ctx, key).Result()
+			if err == redis.Nil {
+				return ErrSessionRevoked
+			}
+			if err != nil {
+				return fmt.Errorf("redis error: %w", err)
+			}
+
+			var sessionData SessionData
+			if err := json.Unmarshal([]byte(val), &sessionData); err != nil {
+				return fmt.Errorf("json unmarshal error: %w", err)
+			}
+
+			event := SecurityEvent{
+				UserID:            userID,
+				TenantID:          sessionData.TenantID,
+				SessionID:         sessionID,
+				UserAgent:         currentUserAgent,
+				ClientIP:          clientIP,
+				PreviousUserAgent: sessionData.UserAgent,
+				PreviousClientIP:  sessionData.ClientIP,
+			}
+
+			// Sessions created before they kept the role and tenant would mint
+			// access tokens bound to no tenant; their users sign in again.
+			if sessionData.Role == "" {
+				if err := m.revoke(ctx, tx, userID, sessionID); err != nil {
+					return err
+				}
+				return ErrSessionRevoked
+			}
+
+			// Impersonation sessions have no refresh token and end with their access token.
+			if sessionData.ImpersonatorID != "" {
+				return ErrSessionMismatch
+			}
+
+			if sessionData.RefreshToken != oldRefreshToken {
+				used := hashToken(oldRefreshToken)
+				last := len(sessionData.UsedTokens) - 1
+
+				if last >= 0 && sessionData.UsedTokens[last] == used && time.Since(time.Unix(sessionData.RotatedAt, 0)) < reuseGrace {
+					return ErrSessionMismatch
+				}
+				if !slices.Contains(sessionData.UsedTokens, used) {
+					return ErrSessionMismatch
+				}
+
+				if err := m.revoke(ctx, tx, userID, sessionID); err != nil {
+					return err
+				}
+				event.Type, event.Action = EventRefreshReuse, ActionRevoked
+				events = append(events, event)
+				return ErrRefreshReused
+			}
+
+			checks := []struct {
+				eventType string
+				policy    string
+				changed   bool
+			}{
+				{EventUserAgentDrift, driftPolicy(m.cfg.UserAgentDrift, DriftReject), userAgentFingerprint(sessionData.UserAgent) != userAgentFingerprint(currentUserAgent)},
+				{EventIPDrift, driftPolicy(m.cfg.IPDrift, DriftAlert), sessionData.ClientIP != clientIP},
+			}
+			for _, check := range checks {
+				if !check.changed {
+					continue
+				}
+
+				event.Type = check.eventType
+				switch check.policy {
+				case DriftRevoke:
+					if err := m.revoke(ctx, tx, userID, sessionID); err != nil {
+						return err
+					}
+					event.Action = ActionRevoked
+					events = append(events, event)
+					return ErrSessionRevoked
+				case DriftReject:
+					event.Action = ActionRejected
+					events = append(events, event)
+					return ErrSessionDrift
+				case DriftAlert:
+					event.Action = ActionAllowed
+					events = append(events, event)
+				}
+			}
+
+			newRefresh = uuid.New().String()
+			newAccess, err = m.generateAccessToken(ctx, userID, sessionID, sessionData.Role, sessionData.TenantID)
+			if err != nil {
+				return err
+			}
+
+			sessionData.UsedTokens = append(sessionData.UsedTokens, hashToken(oldRefreshToken))
+			if len(sessionData.UsedTokens) > maxUsedTokens {
+				sessionData.UsedTokens = sessionData.UsedTokens[len(sessionData.UsedTokens)-maxUsedTokens:]
+			}
+			sessionData.RefreshToken = newRefresh
+			sessionData.RotatedAt = time.Now().Unix()
+			sessionData.ExpiresAt = time.Now().Add(m.cfg.RefreshExpireMinutes).Unix()
+			sessionData.UserAgent = currentUserAgent
+			sessionData.ClientIP = clientIP
+
+			newJSONData, err := json.Marshal(sessionData)
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				m.saveSession(ctx, pipe, userID, sessionID, newJSONData, sessionData.ExpiresAt)
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("redis update error: %w", err)
+			}
+			return nil
+		}, key)
+
+		if !errors.Is(err, redis.TxFailedErr) {
+			break
+		}
+	}
+
+	for _, event := range events {
+		m.emit(ctx, event)
+	}
+
+	if errors.Is(err, redis.TxFailedErr) {
+		return "", "", ErrSessionMismatch
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	return newAccess, newRefresh, nil
+}
+
+func (m *Manager) emit(ctx context.Context, event SecurityEvent) {
+	if m.onEvent != nil {
+		m.onEvent(ctx, event)
+	}
+}
+
+func (m *Manager) revoke(ctx context.Context, tx *redis.Tx, userID, sessionID string) error {
+	_, err := tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		m.removeSession(ctx, pipe, userID, sessionID)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("redis delete error: %w", err)
+	}
+	return nil
+}
+
+func driftPolicy(configured, fallback string) string {
+	if configured == "" {
+		return fallback
+	}
+	return configured
+}
+
+// userAgentFingerprint drops version numbers, so a browser or OS update
+// does not count as the session moving to another device.
+func userAgentFingerprint(userAgent string) string {
+	return versionPattern.ReplaceAllString(userAgent, "")
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
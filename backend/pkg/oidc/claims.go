@@ -0,0 +1,61 @@
+package oidc
+
+import (
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Claims are the claims of a verified ID token. Names may be dotted paths
+// into nested objects, such as Keycloak's "realm_access.roles".
+type Claims jwt.MapClaims
+
+func (c Claims) GetExpirationTime() (*jwt.NumericDate, error) {
+	return jwt.MapClaims(c).GetExpirationTime()
+}
+func (c Claims) GetIssuedAt() (*jwt.NumericDate, error)  { return jwt.MapClaims(c).GetIssuedAt() }
+func (c Claims) GetNotBefore() (*jwt.NumericDate, error) { return jwt.MapClaims(c).GetNotBefore() }
+func (c Claims) GetIssuer() (string, error)              { return jwt.MapClaims(c).GetIssuer() }
+func (c Claims) GetSubject() (string, error)             { return jwt.MapClaims(c).GetSubject() }
+func (c Claims) GetAudience() (jwt.ClaimStrings, error)  { return jwt.MapClaims(c).GetAudience() }
+
+// String returns the claim as a string, or "" when it is missing or not a string.
+func (c Claims) String(name string) string {
+	s, _ := c.lookup(name).(string)
+	return s
+}
+
+// Strings returns a list claim such as groups. A single string counts as a
+// one-element list.
+func (c Claims) Strings(name string) []string {
+	switch v := c.lookup(name).(type) {
+	case string:
+		return []string{v}
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+func (c Claims) lookup(name string) any {
+	if v, ok := c[name]; ok {
+		return v
+	}
+
+	var current any = map[string]any(c)
+	for part := range strings.SplitSeq(name, ".") {
+		obj, ok := current.(map[string]any)
+		if !ok {
+			return nil
+		}
+		current = obj[part]
+	}
+	return current
+}
@@ -0,0 +1,236 @@
+// Package oidc implements the OpenID Connect authorization code flow with PKCE
+// for signing in through a tenant's identity provider.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	discoveryPath = "/.well-known/openid-configuration"
+
+	// metadataTTL bounds how long discovery documents and key sets are reused
+	// before the provider is asked again; an unknown kid refetches at once.
+	metadataTTL    = time.Hour
+	requestTimeout = 10 * time.Second
+	maxBodySize    = 1 << 20
+)
+
+var (
+	ErrDiscovery      = errors.New("oidc discovery failed")
+	ErrExchange       = errors.New("oidc code exchange failed")
+	ErrIDTokenInvalid = errors.New("oidc id token is invalid")
+)
+
+// Provider is the part of the discovery document the code flow needs.
+type Provider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Tokens is the token endpoint response.
+type Tokens struct {
+	AccessToken string `json:"access_token"`
+	IDToken     string `json:"id_token"`
+	TokenType   string `json:"token_type"`
+}
+
+// Client talks to identity providers and caches their metadata. It is safe
+// for concurrent use and shared by every tenant.
+type Client struct {
+	http *http.Client
+
+	mu        sync.Mutex
+	providers map[string]cached[Provider]
+	keys      map[string]cached[map[string]any]
+}
+
+type cached[T any] struct {
+	value     T
+	fetchedAt time.Time
+}
+
+func (c cached[T]) fresh() bool {
+	return time.Since(c.fetchedAt) < metadataTTL
+}
+
+func NewClient() *Client {
+	return &Client{
+		http:      &http.Client{Timeout: requestTimeout},
+		providers: make(map[string]cached[Provider]),
+		keys:      make(map[string]cached[map[string]any]),
+	}
+}
+
+// Discover returns the provider metadata published under issuer. The issuer
+// in the document must match, as required by OpenID Connect Discovery.
+func (c *Client) Discover(ctx context.Context, issuer string) (Provider, error) {
+	issuer = strings.TrimSuffix(issuer, "/")
+
+	c.mu.Lock()
+	entry, ok := c.providers[issuer]
+	c.mu.Unlock()
+	if ok && entry.fresh() {
+		return entry.value, nil
+	}
+
+	var provider Provider
+	if err := c.getJSON(ctx, issuer+discoveryPath, &provider); err != nil {
+		return Provider{}, fmt.Errorf("%w: %w", ErrDiscovery, err)
+	}
+	if strings.TrimSuffix(provider.Issuer, "/") != issuer {
+		return Provider{}, fmt.Errorf("%w: issuer %q does not match %q", ErrDiscovery, provider.Issuer, issuer)
+	}
+	if provider.AuthorizationEndpoint == "" || provider.TokenEndpoint == "" || provider.JWKSURI == "" {
+		return Provider{}, fmt.Errorf("%w: incomplete discovery document", ErrDiscovery)
+	}
+
+	c.mu.Lock()
+	c.providers[issuer] = cached[Provider]{value: provider, fetchedAt: time.Now()}
+	c.mu.Unlock()
+
+	return provider, nil
+}
+
+// AuthCodeURL builds the URL the browser is sent to for signing in.
+func AuthCodeURL(provider Provider, clientID, redirectURL string, scopes []string, state, nonce, codeChallenge string) string {
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {clientID},
+		"redirect_uri":          {redirectURL},
+		"scope":                 {strings.Join(scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {"S256"},
+	}
+
+	sep := "?"
+	if strings.Contains(provider.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return provider.AuthorizationEndpoint + sep + params.Encode()
+}
+
+// Exchange trades the authorization code and PKCE verifier for tokens,
+// authenticating with client_secret_basic when a secret is set.
+func (c *Client) Exchange(ctx context.Context, provider Provider, clientID, clientSecret, redirectURL, code, codeVerifier string) (Tokens, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURL},
+		"code_verifier": {codeVerifier},
+	}
+	if clientSecret == "" {
+		form.Set("client_id", clientID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Tokens{}, fmt.Errorf("%w: %w", ErrExchange, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if clientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(clientSecret))
+	}
+
+	var tokens Tokens
+	if err := c.do(req, &tokens); err != nil {
+		return Tokens{}, fmt.Errorf("%w: %w", ErrExchange, err)
+	}
+	if tokens.IDToken == "" {
+		return Tokens{}, fmt.Errorf("%w: response has no id_token", ErrExchange)
+	}
+
+	return tokens, nil
+}
+
+// VerifyIDToken checks the signature, issuer, audience, expiry and nonce of
+// the ID token and returns its claims.
+func (c *Client) VerifyIDToken(ctx context.Context, provider Provider, clientID, nonce, rawIDToken string) (Claims, error) {
+	claims := Claims{}
+	_, err := jwt.ParseWithClaims(rawIDToken, &claims, c.keyFunc(ctx, provider.JWKSURI),
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "PS256"}),
+		jwt.WithIssuer(provider.Issuer),
+		jwt.WithAudience(clientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrIDTokenInvalid, err)
+	}
+
+	if claims.String("nonce") != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrIDTokenInvalid)
+	}
+	if claims.String("sub") == "" {
+		return nil, fmt.Errorf("%w: missing sub", ErrIDTokenInvalid)
+	}
+
+	return claims, nil
+}
+
+// NewPKCE returns a code verifier and its S256 challenge (RFC 7636).
+func NewPKCE() (verifier, challenge string, err error) {
+	verifier, err = RandomString()
+	if err != nil {
+		return "", "", err
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// RandomString returns 256 random bits encoded for use in URLs, as used for
+// state, nonce and PKCE verifiers.
+func RandomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (c *Client) getJSON(ctx context.Context, endpoint string, dest any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	return c.do(req, dest)
+}
+
+func (c *Client) do(req *http.Request, dest any) error {
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: status %d: %s", req.Method, req.URL.Redacted(), resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return json.Unmarshal(body, dest)
+}
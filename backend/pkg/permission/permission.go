@@ -0,0 +1,96 @@
+// Package permission - Named permissions checked by the authorizer
+//
+// A permission is "resource:action". Protected routes declare the permission
+// they require when they are registered, and Casbin policies grant
+// permissions to roles, so policies don't depend on the URL layout. In a
+// policy, "*" as the resource or action matches any.
+package permission
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/casbin/casbin/v3/util"
+)
+
+const (
+	Any = "*:*"
+
+	UsersRead          = "users:read"
+	UsersBlock         = "users:block"
+	UsersUnlock        = "users:unlock"
+	UsersResetPassword = "users:reset-password"
+	UsersImpersonate   = "users:impersonate"
+
+	RolesRead   = "roles:read"
+	RolesManage = "roles:manage"
+	RolesAssign = "roles:assign"
+
+	TenantSettingsRead   = "tenant-settings:read"
+	TenantSettingsUpdate = "tenant-settings:update"
+
+	ServiceAccountsRead   = "service-accounts:read"
+	ServiceAccountsManage = "service-accounts:manage"
+
+	PatientsRead       = "patients:read"
+	AppointmentsCreate = "appointments:create"
+	VitalsCreate       = "vitals:create"
+
+	TestOwner  = "test:owner"
+	TestDoctor = "test:doctor"
+	TestNurse  = "test:nurse"
+)
+
+// Known lists every permission a role can be granted, sorted: the ones routes
+// declare and the ones of resources that have no routes yet.
+var Known = slices.Sorted(slices.Values([]string{
+	UsersRead, UsersBlock, UsersUnlock, UsersResetPassword, UsersImpersonate,
+	RolesRead, RolesManage, RolesAssign,
+	TenantSettingsRead, TenantSettingsUpdate,
+	ServiceAccountsRead, ServiceAccountsManage,
+	PatientsRead, AppointmentsCreate, VitalsCreate,
+	TestOwner, TestDoctor, TestNurse,
+}))
+
+// IsKnown reports whether the permission, which may use "*" as the resource
+// or action, covers at least one known permission.
+func IsKnown(name string) bool {
+	resource, action := Split(name)
+	return slices.ContainsFunc(Known, func(known string) bool {
+		knownResource, knownAction := Split(known)
+		return (resource == "*" || resource == knownResource) && (action == "*" || action == knownAction)
+	})
+}
+
+// Covers reports whether pattern, as a policy or an API key scope that may
+// use "*", covers the permission name.
+func Covers(pattern, name string) bool {
+	resource, action := Split(name)
+	patternResource, patternAction := Split(pattern)
+	return util.KeyMatch(resource, patternResource) && util.KeyMatch(action, patternAction)
+}
+
+// Split returns the resource and action of a permission. A name without an
+// action is the resource with any action.
+func Split(permission string) (resource, action string) {
+	resource, action, ok := strings.Cut(permission, ":")
+	if !ok {
+		return permission, "*"
+	}
+	return resource, action
+}
+
+func Join(resource, action string) string {
+	return resource + ":" + action
+}
+
+// BranchDomain is the Casbin domain of roles granted in one branch of a
+// tenant. Roles granted in the tenant domain itself apply to every branch.
+func BranchDomain(tenantID, branchID string) string {
+	return tenantID + "/" + branchID
+}
+
+// BranchOf returns the branch of a domain that belongs to the tenant.
+func BranchOf(tenantID, domain string) (string, bool) {
+	return strings.CutPrefix(domain, tenantID+"/")
+}
@@ -0,0 +1,73 @@
+package permission
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+//go:embed templates/*.yaml
+var templateFiles embed.FS
+
+// Template is the default set of permissions of a built-in role. Raising its
+// version makes the next start upgrade every tenant to the new set.
+type Template struct {
+	Role        string   `yaml:"role"`
+	Version     int      `yaml:"version"`
+	Description string   `yaml:"description"`
+	Permissions []string `yaml:"permissions"`
+}
+
+// Templates are the role templates in templates/, keyed by role.
+var Templates = mustLoadTemplates()
+
+func mustLoadTemplates() map[string]Template {
+	templates, err := loadTemplates(templateFiles)
+	if err != nil {
+		panic(err)
+	}
+	return templates
+}
+
+func loadTemplates(files fs.FS) (map[string]Template, error) {
+	names, err := fs.Glob(files, "templates/*.yaml")
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make(map[string]Template, len(names))
+	for _, name := range names {
+		data, err := fs.ReadFile(files, name)
+		if err != nil {
+			return nil, err
+		}
+
+		var template Template
+		if err := yaml.Unmarshal(data, &template); err != nil {
+			return nil, fmt.Errorf("policy template %s: %w", name, err)
+		}
+
+		if template.Role != strings.TrimSuffix(path.Base(name), ".yaml") {
+			return nil, fmt.Errorf("policy template %s: role %q does not match the file name", name, template.Role)
+		}
+		if template.Version < 1 {
+			return nil, fmt.Errorf("policy template %s: version must be positive", name)
+		}
+		for _, permission := range template.Permissions {
+			if !strings.Contains(permission, ":") {
+				return nil, fmt.Errorf("policy template %s: permission %q is not resource:action", name, permission)
+			}
+			if !IsKnown(permission) {
+				return nil, fmt.Errorf("policy template %s: unknown permission %q", name, permission)
+			}
+		}
+
+		templates[template.Role] = template
+	}
+
+	return templates, nil
+}
@@ -35,6 +35,10 @@ func New(cfg *config.Postgres, isDev bool, log logger.Logger) (*gorm.DB, error)
 		return nil, err
 	}
 
+	if err := db.Use(rowLevelSecurity{}); err != nil {
+		return nil, err
+	}
+
 	sqlDB, err := db.DB()
 	if err != nil {
 		return nil, err
@@ -52,5 +56,9 @@ func New(cfg *config.Postgres, isDev bool, log logger.Logger) (*gorm.DB, error)
 		return nil, err
 	}
 
+	if err := checkRole(ctx, sqlDB); err != nil {
+		return nil, err
+	}
+
 	return db, nil
 }
\ No newline at end of file
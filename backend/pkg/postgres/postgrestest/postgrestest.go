@@ -0,0 +1,112 @@
+// Package postgrestest - Migrated test database for DB-backed tests
+//
+// Tests that need PostgreSQL run against the database in
+// EIR_TEST_POSTGRES_DSN and are skipped when it is unset. The role must not be
+// a superuser or have BYPASSRLS, since either ignores row-level security.
+package postgrestest
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/asliddinberdiev/eirsystem/config"
+	"github.com/asliddinberdiev/eirsystem/pkg/logger"
+	"github.com/asliddinberdiev/eirsystem/pkg/postgres"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"gorm.io/gorm"
+)
+
+const dsnEnv = "EIR_TEST_POSTGRES_DSN"
+
+// Open migrates the test database and connects to it the way the application
+// does, with row-level security applied to every session.
+func Open(t testing.TB) *gorm.DB {
+	t.Helper()
+
+	dsn := testDSN(t)
+	connConfig, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		t.Fatalf("parse %s: %v", dsnEnv, err)
+	}
+
+	cfg := &config.Postgres{
+		Host:     connConfig.Host,
+		Port:     int(connConfig.Port),
+		User:     connConfig.User,
+		Password: connConfig.Password,
+		DBName:   connConfig.Database,
+		SSLMode:  "disable",
+		TimeZone: "UTC",
+	}
+	if connConfig.TLSConfig != nil {
+		cfg.SSLMode = "require"
+	}
+
+	migrationDB, err := postgres.NewMigrationDB(cfg)
+	if err != nil {
+		t.Fatalf("open migration database: %v", err)
+	}
+	defer migrationDB.Close()
+
+	if err := postgres.RunMigrations(migrationDB, migrationsDir()); err != nil {
+		t.Fatal(err)
+	}
+
+	log, err := logger.New(config.Logger{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// New refuses roles that bypass row-level security.
+	db, err := postgres.New(cfg, false, log)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+
+	return db
+}
+
+func testDSN(t testing.TB) string {
+	t.Helper()
+
+	dsn := os.Getenv(dsnEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", dsnEnv)
+	}
+	return dsn
+}
+
+// CreateTenant adds a tenant that is deleted, with all of its rows, when the
+// test ends.
+func CreateTenant(t testing.TB, db *gorm.DB) string {
+	t.Helper()
+
+	id := uuid.New().String()
+	err := postgres.Bypass(db).Exec(
+		"INSERT INTO tenants (id, name, slug, subscription_end_date) VALUES (?, ?, ?, NOW() + INTERVAL '1 day')",
+		id, "Test "+id[:8], "test-"+id,
+	).Error
+	if err != nil {
+		t.Fatalf("create tenant: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := postgres.Bypass(db).Exec("DELETE FROM tenants WHERE id = ?", id).Error; err != nil {
+			t.Errorf("delete tenant %s: %v", id, err)
+		}
+	})
+	return id
+}
+
+func migrationsDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "..", "migrations")
+}
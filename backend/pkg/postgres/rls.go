@@ -0,0 +1,189 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/asliddinberdiev/eirsystem/config"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/gorm"
+)
+
+// Tenant tables have row-level security: a transaction only sees the rows of
+// the tenant in app.tenant_id, or every row when app.bypass_rls is on. With
+// neither set it sees none, so a statement that isn't scoped fails closed.
+const (
+	tenantSetting = "rls:tenant_id"
+	bypassSetting = "rls:bypass"
+
+	startedSetting = "rls:started_transaction"
+)
+
+var (
+	ErrScopeWithoutTransaction = errors.New("row-level security scope needs a transaction for Row and Rows; use Transaction")
+	ErrRoleBypassesRLS         = errors.New("database role is a superuser or has BYPASSRLS, so row-level security does not isolate tenants")
+)
+
+// Tenant scopes the statements of the returned session to the rows of one
+// tenant. Repositories use it wherever the caller's tenant is known.
+func Tenant(db *gorm.DB, tenantID string) *gorm.DB {
+	return db.Set(tenantSetting, tenantID)
+}
+
+// Bypass lets the statements of the returned session see every tenant. It is
+// meant for system callers and for lookups that resolve the tenant, such as
+// finding the user of an access token.
+func Bypass(db *gorm.DB) *gorm.DB {
+	return db.Set(bypassSetting, true)
+}
+
+// checkRole fails when the connected role skips row-level security. Superusers
+// and BYPASSRLS roles do even under FORCE ROW LEVEL SECURITY, which would leave
+// the tenants unisolated.
+func checkRole(ctx context.Context, sqlDB *sql.DB) error {
+	var bypasses bool
+	err := sqlDB.QueryRowContext(ctx, "SELECT rolsuper OR rolbypassrls FROM pg_roles WHERE rolname = current_user").Scan(&bypasses)
+	if err != nil {
+		return err
+	}
+	if bypasses {
+		return ErrRoleBypassesRLS
+	}
+	return nil
+}
+
+// NewMigrationDB opens a connection pool for migrations, which run with the
+// row-level security bypass on.
+func NewMigrationDB(cfg *config.Postgres) (*sql.DB, error) {
+	connConfig, err := pgx.ParseConfig(cfg.GetDSN())
+	if err != nil {
+		return nil, err
+	}
+	connConfig.RuntimeParams["app.bypass_rls"] = "on"
+
+	return stdlib.OpenDB(*connConfig), nil
+}
+
+// rowLevelSecurity applies the scope of a session to each of its statements.
+// The settings are transaction-local, so a statement outside a transaction is
+// wrapped in one of its own.
+type rowLevelSecurity struct{}
+
+func (rowLevelSecurity) Name() string {
+	return "row_level_security"
+}
+
+func (rowLevelSecurity) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+
+	if err := callbacks.Create().After("gorm:begin_transaction").Before("gorm:before_create").Register("rls:scope", scope); err != nil {
+		return err
+	}
+	if err := callbacks.Create().After("gorm:commit_or_rollback_transaction").Register("rls:commit", commit); err != nil {
+		return err
+	}
+
+	if err := callbacks.Update().After("gorm:begin_transaction").Before("gorm:setup_reflect_value").Register("rls:scope", scope); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("gorm:commit_or_rollback_transaction").Register("rls:commit", commit); err != nil {
+		return err
+	}
+
+	if err := callbacks.Delete().After("gorm:begin_transaction").Before("gorm:before_delete").Register("rls:scope", scope); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().After("gorm:commit_or_rollback_transaction").Register("rls:commit", commit); err != nil {
+		return err
+	}
+
+	if err := callbacks.Query().Before("gorm:query").Register("rls:scope", scope); err != nil {
+		return err
+	}
+	if err := callbacks.Query().After("gorm:after_query").Register("rls:commit", commit); err != nil {
+		return err
+	}
+
+	if err := callbacks.Raw().Before("gorm:raw").Register("rls:scope", scope); err != nil {
+		return err
+	}
+	if err := callbacks.Raw().After("gorm:raw").Register("rls:commit", commit); err != nil {
+		return err
+	}
+
+	// Rows stay open after the callback returns, so there is no point where a
+	// transaction of our own could be committed.
+	return callbacks.Row().Before("gorm:row").Register("rls:scope", scopeInTransaction)
+}
+
+func scope(db *gorm.DB) {
+	tenantID, bypass, ok := settings(db)
+	if !ok || db.Error != nil {
+		return
+	}
+
+	if _, inTransaction := db.Statement.ConnPool.(gorm.TxCommitter); !inTransaction {
+		tx := db.Begin()
+		if tx.Error != nil {
+			db.AddError(tx.Error)
+			return
+		}
+		db.Statement.ConnPool = tx.Statement.ConnPool
+		db.InstanceSet(startedSetting, true)
+	}
+
+	apply(db, tenantID, bypass)
+}
+
+func scopeInTransaction(db *gorm.DB) {
+	tenantID, bypass, ok := settings(db)
+	if !ok || db.Error != nil {
+		return
+	}
+
+	if _, inTransaction := db.Statement.ConnPool.(gorm.TxCommitter); !inTransaction {
+		db.AddError(ErrScopeWithoutTransaction)
+		return
+	}
+
+	apply(db, tenantID, bypass)
+}
+
+func commit(db *gorm.DB) {
+	if _, ok := db.InstanceGet(startedSetting); !ok {
+		return
+	}
+
+	if db.Error != nil {
+		db.Rollback()
+	} else {
+		db.Commit()
+	}
+	db.Statement.ConnPool = db.ConnPool
+}
+
+func apply(db *gorm.DB, tenantID string, bypass bool) {
+	bypassValue := "off"
+	if bypass {
+		bypassValue = "on"
+	}
+
+	_, err := db.Statement.ConnPool.ExecContext(
+		db.Statement.Context,
+		"SELECT set_config('app.tenant_id', $1, true), set_config('app.bypass_rls', $2, true)",
+		tenantID, bypassValue,
+	)
+	db.AddError(err)
+}
+
+func settings(db *gorm.DB) (tenantID string, bypass bool, ok bool) {
+	if value, found := db.Get(bypassSetting); found {
+		bypass, _ = value.(bool)
+	}
+	if value, found := db.Get(tenantSetting); found {
+		tenantID, _ = value.(string)
+	}
+	return tenantID, bypass, bypass || tenantID != ""
+}
@@ -0,0 +1,201 @@
+package postgres_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/asliddinberdiev/eirsystem/pkg/postgres"
+	"github.com/asliddinberdiev/eirsystem/pkg/postgres/postgrestest"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type user struct {
+	ID           string
+	TenantID     string
+	FullName     string
+	Username     string
+	PasswordHash string
+	Role         string
+}
+
+func (user) TableName() string { return "users" }
+
+type patient struct {
+	ID       string
+	TenantID string
+	FullName string
+	Phone    string
+}
+
+func (patient) TableName() string { return "patients" }
+
+type fixture struct {
+	db       *gorm.DB
+	tenantA  string
+	tenantB  string
+	userA    user
+	userB    user
+	patientA patient
+	patientB patient
+}
+
+// newFixture creates two tenants with a user and a patient each.
+func newFixture(t *testing.T) fixture {
+	db := postgrestest.Open(t)
+
+	f := fixture{db: db, tenantA: postgrestest.CreateTenant(t, db), tenantB: postgrestest.CreateTenant(t, db)}
+	f.userA, f.patientA = f.seed(t, f.tenantA)
+	f.userB, f.patientB = f.seed(t, f.tenantB)
+	return f
+}
+
+func (f fixture) seed(t *testing.T, tenantID string) (user, patient) {
+	t.Helper()
+
+	u := newUser(tenantID)
+	if err := postgres.Tenant(f.db, tenantID).Create(&u).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	p := newPatient(tenantID)
+	if err := postgres.Tenant(f.db, tenantID).Create(&p).Error; err != nil {
+		t.Fatalf("create patient: %v", err)
+	}
+	return u, p
+}
+
+func (f fixture) tenants() []string {
+	return []string{f.tenantA, f.tenantB}
+}
+
+func newUser(tenantID string) user {
+	id := uuid.New().String()
+	return user{ID: id, TenantID: tenantID, FullName: "Test User", Username: "user-" + id[:8], PasswordHash: "x", Role: "doctor"}
+}
+
+func newPatient(tenantID string) patient {
+	return patient{ID: uuid.New().String(), TenantID: tenantID, FullName: "Test Patient", Phone: "+998900000000"}
+}
+
+func TestTenantCannotReadOtherTenant(t *testing.T) {
+	f := newFixture(t)
+	scoped := postgres.Tenant(f.db, f.tenantA)
+
+	var users []user
+	if err := scoped.Where("tenant_id IN ?", f.tenants()).Find(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 1 || users[0].ID != f.userA.ID {
+		t.Errorf("tenant A read users %v, want only %s", users, f.userA.ID)
+	}
+
+	var patients []patient
+	if err := scoped.Where("tenant_id IN ?", f.tenants()).Find(&patients).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(patients) != 1 || patients[0].ID != f.patientA.ID {
+		t.Errorf("tenant A read patients %v, want only %s", patients, f.patientA.ID)
+	}
+
+	err := scoped.Where("id = ?", f.userB.ID).Take(&user{}).Error
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("tenant A reading user of B: got %v, want %v", err, gorm.ErrRecordNotFound)
+	}
+}
+
+func TestTenantCannotUpdateOtherTenant(t *testing.T) {
+	f := newFixture(t)
+	scoped := postgres.Tenant(f.db, f.tenantA)
+
+	result := scoped.Model(&user{}).Where("id = ?", f.userB.ID).Update("full_name", "Changed")
+	if result.Error != nil || result.RowsAffected != 0 {
+		t.Errorf("tenant A updating user of B: %d rows, %v", result.RowsAffected, result.Error)
+	}
+
+	result = scoped.Model(&patient{}).Where("id = ?", f.patientB.ID).Update("full_name", "Changed")
+	if result.Error != nil || result.RowsAffected != 0 {
+		t.Errorf("tenant A updating patient of B: %d rows, %v", result.RowsAffected, result.Error)
+	}
+
+	var u user
+	if err := postgres.Bypass(f.db).Where("id = ?", f.userB.ID).Take(&u).Error; err != nil {
+		t.Fatal(err)
+	}
+	var p patient
+	if err := postgres.Bypass(f.db).Where("id = ?", f.patientB.ID).Take(&p).Error; err != nil {
+		t.Fatal(err)
+	}
+	if u.FullName != f.userB.FullName || p.FullName != f.patientB.FullName {
+		t.Errorf("rows of tenant B changed: user %q, patient %q", u.FullName, p.FullName)
+	}
+
+	result = scoped.Model(&patient{}).Where("id = ?", f.patientA.ID).Update("full_name", "Changed")
+	if result.Error != nil || result.RowsAffected != 1 {
+		t.Errorf("tenant A updating its own patient: %d rows, %v", result.RowsAffected, result.Error)
+	}
+}
+
+func TestTenantCannotInsertIntoOtherTenant(t *testing.T) {
+	f := newFixture(t)
+	scoped := postgres.Tenant(f.db, f.tenantA)
+
+	u := newUser(f.tenantB)
+	if err := scoped.Create(&u).Error; err == nil {
+		t.Error("tenant A inserted a user into tenant B")
+	}
+
+	p := newPatient(f.tenantB)
+	if err := scoped.Create(&p).Error; err == nil {
+		t.Error("tenant A inserted a patient into tenant B")
+	}
+
+	var count int64
+	if err := postgres.Bypass(f.db).Model(&user{}).Where("tenant_id = ?", f.tenantB).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("tenant B has %d users, want 1", count)
+	}
+}
+
+func TestUnscopedSessionSeesNothing(t *testing.T) {
+	f := newFixture(t)
+
+	var users []user
+	if err := f.db.Where("tenant_id IN ?", f.tenants()).Find(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 0 {
+		t.Errorf("unscoped session read %d users, want none", len(users))
+	}
+
+	var patients []patient
+	if err := f.db.Where("tenant_id IN ?", f.tenants()).Find(&patients).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(patients) != 0 {
+		t.Errorf("unscoped session read %d patients, want none", len(patients))
+	}
+}
+
+func TestBypassSeesEveryTenant(t *testing.T) {
+	f := newFixture(t)
+	bypass := postgres.Bypass(f.db)
+
+	var users []user
+	if err := bypass.Where("tenant_id IN ?", f.tenants()).Find(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 {
+		t.Errorf("bypass read %d users, want 2", len(users))
+	}
+
+	var patients []patient
+	if err := bypass.Where("tenant_id IN ?", f.tenants()).Find(&patients).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(patients) != 2 {
+		t.Errorf("bypass read %d patients, want 2", len(patients))
+	}
+}
@@ -12,6 +12,9 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+// Nil is returned by Client when a key does not exist.
+const Nil = redis.Nil
+
 type RedisClient struct {
 	Client *redis.Client
 }
@@ -2,8 +2,12 @@
 package response
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/asliddinberdiev/eirsystem/pkg/codes"
 	"github.com/asliddinberdiev/eirsystem/pkg/logger"
@@ -32,7 +36,38 @@ func Success(c *gin.Context, code codes.Code, data any) {
 	})
 }
 
+// SuccessWithETag responds like Success and tags data with an ETag, so clients
+// can cache it. A request whose If-None-Match holds the current tag gets 304
+// Not Modified without a body.
+func SuccessWithETag(c *gin.Context, code codes.Code, data any) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		Success(c, code, data)
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "private, no-cache")
+
+	for _, tag := range strings.Split(c.GetHeader("If-None-Match"), ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == etag {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+
+	Success(c, code, data)
+}
+
 func Error(c *gin.Context, log logger.Logger, code codes.Code, err error) {
+	ErrorWithData(c, log, code, err, nil)
+}
+
+// ErrorWithData aborts like Error and also returns data the client needs to
+// recover from the error, such as how long to wait before retrying.
+func ErrorWithData(c *gin.Context, log logger.Logger, code codes.Code, err error, data any) {
 	reqID := c.GetString("requestID")
 	status := code.HTTPStatus()
 
@@ -71,6 +106,7 @@ func Error(c *gin.Context, log logger.Logger, code codes.Code, err error) {
 		Success:   false,
 		Code:      int(code),
 		Message:   code.String(),
+		Data:      data,
 		Error:     technicalError,
 		RequestID: reqID,
 	})
@@ -5,8 +5,8 @@ import (
 	"fmt"
 
 	"github.com/asliddinberdiev/eirsystem/config"
+	"github.com/asliddinberdiev/eirsystem/pkg/hasher"
 	"github.com/asliddinberdiev/eirsystem/pkg/logger"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
@@ -32,7 +32,7 @@ func SeedSystemAdmin(log logger.Logger, db *gorm.DB, cfg config.SeedSystemAdmin)
 
 	log.Info("System Admin not found. Creating...")
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(cfg.Password), bcrypt.DefaultCost)
+	hashedPassword, err := hasher.Hash(cfg.Password)
 	if err != nil {
 		return fmt.Errorf("error hashing password: %w", err)
 	}
@@ -42,7 +42,7 @@ func SeedSystemAdmin(log logger.Logger, db *gorm.DB, cfg config.SeedSystemAdmin)
 		VALUES (NULL, ?, ?, ?, 'system', ?, TRUE)
 	`
 
-	if err := db.Exec(insertSQL, cfg.FullName, cfg.Username, string(hashedPassword), cfg.Phone).Error; err != nil {
+	if err := db.Exec(insertSQL, cfg.FullName, cfg.Username, hashedPassword, cfg.Phone).Error; err != nil {
 		return fmt.Errorf("error saving admin: %w", err)
 	}
 
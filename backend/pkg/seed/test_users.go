@@ -5,14 +5,15 @@ import (
 	"time"
 
 	"github.com/asliddinberdiev/eirsystem/internal/model"
+	"github.com/asliddinberdiev/eirsystem/pkg/hasher"
 	"github.com/asliddinberdiev/eirsystem/pkg/logger"
+	"github.com/asliddinberdiev/eirsystem/pkg/permission"
 	casbinlib "github.com/casbin/casbin/v3"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
-func SeedTestUsers(log logger.Logger, db *gorm.DB, enforcer *casbinlib.Enforcer) error {
+func SeedTestUsers(log logger.Logger, db *gorm.DB, enforcer *casbinlib.SyncedEnforcer) error {
 	log.Info("Seeding Test Users (Owner, Doctor, Nurse)...")
 	
 	tenantID := uuid.New().String()
@@ -20,7 +21,6 @@ func SeedTestUsers(log logger.Logger, db *gorm.DB, enforcer *casbinlib.Enforcer)
 		ID:                  tenantID,
 		Name:                "Test Tenant",
 		Slug:                "test-tenant",
-		OwnerID:             uuid.New().String(),
 		IsActive:            true,
 		SubscriptionEndDate: time.Now().AddDate(1, 0, 0),
 	}
@@ -49,8 +49,8 @@ func SeedTestUsers(log logger.Logger, db *gorm.DB, enforcer *casbinlib.Enforcer)
 
 	for _, u := range users {
 		var exists bool
-		checkSQL := `SELECT EXISTS(SELECT 1 FROM users WHERE username = ?)`
-		if err := db.Raw(checkSQL, u.Username).Scan(&exists).Error; err != nil {
+		checkSQL := `SELECT EXISTS(SELECT 1 FROM users WHERE tenant_id = ? AND username = ?)`
+		if err := db.Raw(checkSQL, u.ClinicID, u.Username).Scan(&exists).Error; err != nil {
 			return fmt.Errorf("error checking user %s: %w", u.Username, err)
 		}
 
@@ -59,7 +59,7 @@ func SeedTestUsers(log logger.Logger, db *gorm.DB, enforcer *casbinlib.Enforcer)
 			continue
 		}
 
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
+		hashedPassword, err := hasher.Hash(u.Password)
 		if err != nil {
 			return fmt.Errorf("error hashing password for %s: %w", u.Role, err)
 		}
@@ -69,29 +69,23 @@ func SeedTestUsers(log logger.Logger, db *gorm.DB, enforcer *casbinlib.Enforcer)
             VALUES (?, ?, ?, ?, ?, '123456789', TRUE) RETURNING id
         `
 		var userID string
-		if err := db.Raw(insertSQL, u.ClinicID, u.FullName, u.Username, string(hashedPassword), u.Role).Scan(&userID).Error; err != nil {
+		if err := db.Raw(insertSQL, u.ClinicID, u.FullName, u.Username, hashedPassword, u.Role).Scan(&userID).Error; err != nil {
 			return fmt.Errorf("error creating user %s: %w", u.Role, err)
 		}
 
-		if _, err := enforcer.AddGroupingPolicy(userID, u.Role, u.ClinicID); err != nil {
-			return fmt.Errorf("error adding grouping policy for %s: %w", u.Role, err)
+		if u.Role == "owner" {
+			if err := db.Model(testTenant).Update("owner_id", userID).Error; err != nil {
+				return fmt.Errorf("error linking owner: %w", err)
+			}
 		}
 
-		var policies [][]string
-
-		switch u.Role {
-		case "owner":
-			policies = append(policies, []string{"owner", u.ClinicID, "/api/v1/test/owner", "GET"})
-			policies = append(policies, []string{"owner", u.ClinicID, "/api/v1/test/doctor", "GET"})
-			policies = append(policies, []string{"owner", u.ClinicID, "/api/v1/test/nurse", "GET"})
-		case "doctor":
-			policies = append(policies, []string{"doctor", u.ClinicID, "/api/v1/test/doctor", "GET"})
-		case "nurse":
-			policies = append(policies, []string{"nurse", u.ClinicID, "/api/v1/test/nurse", "GET"})
+		if _, err := enforcer.AddGroupingPolicy(userID, u.Role, u.ClinicID); err != nil {
+			return fmt.Errorf("error adding grouping policy for %s: %w", u.Role, err)
 		}
 
-		for _, policy := range policies {
-			if _, err := enforcer.AddPolicy(policy[0], policy[1], policy[2], policy[3]); err != nil {
+		for _, name := range permission.Templates[u.Role].Permissions {
+			obj, act := permission.Split(name)
+			if _, err := enforcer.AddPolicy(u.Role, u.ClinicID, obj, act); err != nil {
 				log.Error("Error adding permission policy", logger.Error(err))
 				return err
 			}
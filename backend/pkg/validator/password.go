@@ -0,0 +1,56 @@
+package validator
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PasswordPolicy lists the rules a new password has to satisfy.
+type PasswordPolicy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+}
+
+// Password checks the password against the policy and reports every rule it
+// breaks, so the client can show them all at once.
+func Password(password string, policy PasswordPolicy) error {
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			symbol = true
+		}
+	}
+
+	var messages []string
+	if utf8.RuneCountInString(password) < policy.MinLength {
+		messages = append(messages, fmt.Sprintf("password: minimum length is %d", policy.MinLength))
+	}
+	if policy.RequireUpper && !upper {
+		messages = append(messages, "password: must contain an uppercase letter")
+	}
+	if policy.RequireLower && !lower {
+		messages = append(messages, "password: must contain a lowercase letter")
+	}
+	if policy.RequireDigit && !digit {
+		messages = append(messages, "password: must contain a digit")
+	}
+	if policy.RequireSymbol && !symbol {
+		messages = append(messages, "password: must contain a symbol")
+	}
+
+	if len(messages) > 0 {
+		return &Error{Messages: messages}
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+#!/bin/sh
+# Creates the role the application connects as and hands it the database, so
+# that its migrations create tables it owns. The role is neither a superuser
+# nor BYPASSRLS: either would skip row-level security, and the application
+# refuses to start with such a role.
+#
+# Postgres runs this only when it initializes an empty data volume. For an
+# existing volume, run it by hand and transfer the ownership of the existing
+# tables to the role.
+set -e
+
+psql -v ON_ERROR_STOP=1 --username "$POSTGRES_USER" --dbname "$POSTGRES_DB" \
+	-v app_user="$APP_DB_USER" -v app_password="$APP_DB_PASSWORD" -v db="$POSTGRES_DB" <<'SQL'
+CREATE ROLE :"app_user" LOGIN NOSUPERUSER NOBYPASSRLS NOCREATEDB NOCREATEROLE PASSWORD :'app_password';
+ALTER DATABASE :"db" OWNER TO :"app_user";
+ALTER SCHEMA public OWNER TO :"app_user";
+SQL